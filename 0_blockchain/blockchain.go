@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
-	"time"
 )
 
 var Empty [sha256.Size]byte
@@ -23,8 +22,8 @@ type Block struct {
 	Hash              []byte
 }
 
-func NewBlock(data, previousBlockHash []byte) Block {
-	timestamp := time.Now().Unix()
+func NewBlock(clock Clock, data, previousBlockHash []byte) Block {
+	timestamp := clock.Now().Unix()
 	t := encodeUint64(uint64(timestamp))
 	hash := sha256.Sum256(bytes.Join([][]byte{t, data, previousBlockHash},
 		[]byte{}))
@@ -44,6 +43,7 @@ func (b Block) Verify() bool {
 }
 
 type Blockchain struct {
+	clock  Clock
 	blocks []*Block
 }
 
@@ -55,12 +55,12 @@ func (b *Blockchain) Append(data []byte) {
 	} else {
 		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
 	}
-	blk := NewBlock(data, previousBlockHash)
+	blk := NewBlock(b.clock, data, previousBlockHash)
 	b.blocks = append(b.blocks, &blk)
 }
 
 func (b Blockchain) Block(block int) (Block, error) {
-	if block > len(b.blocks) {
+	if block < 0 || block >= len(b.blocks) {
 		return Block{}, fmt.Errorf("invalid block: %v", block)
 	}
 	return *b.blocks[block], nil
@@ -70,9 +70,10 @@ func (b Blockchain) Len() int {
 	return len(b.blocks)
 }
 
-func NewBlockChain(data []byte) *Blockchain {
-	blk := NewBlock(data, Empty[:])
+func NewBlockChain(clock Clock, data []byte) *Blockchain {
+	blk := NewBlock(clock, data, Empty[:])
 	return &Blockchain{
+		clock:  clock,
 		blocks: []*Block{&blk},
 	}
 }
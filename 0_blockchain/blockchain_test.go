@@ -7,21 +7,24 @@ import (
 	"time"
 )
 
-func (b Block) dump(t *testing.T) {
-	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0))
+func (b Block) dump(t logger) {
+	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0).UTC())
 	t.Logf("PreviousBlockHash: %x\n", b.PreviousBlockHash)
 	t.Logf("Hash             : %x\n", b.Hash)
 	t.Logf("Data             : %s\n", string(b.Data))
 }
 
 func newBlockChain() *Blockchain {
-	b := NewBlockChain([]byte("Decred is money!"))
+	clock := NewManualClock(time.Unix(1538345873, 0))
+	b := NewBlockChain(clock, []byte("Decred is money!"))
+	clock.Advance(10 * time.Minute)
 	b.Append([]byte("Send 1 Decred to Alice"))
+	clock.Advance(10 * time.Minute)
 	b.Append([]byte("Send 2 Decred to Bob"))
 	return b
 }
 
-func (b Blockchain) dumpBlockchain(t *testing.T) error {
+func (b Blockchain) dumpBlockchain(t logger) error {
 	for i := 0; i < b.Len(); i++ {
 		t.Logf("%v", strings.Repeat("=", 80))
 		t.Logf("Height           : %v", i)
@@ -39,7 +42,7 @@ func (b Blockchain) dumpBlockchain(t *testing.T) error {
 }
 
 func (b *Blockchain) corrupt(block int, data []byte) error {
-	if block > len(b.blocks) {
+	if block < 0 || block >= len(b.blocks) {
 		return fmt.Errorf("invalid block: %v", block)
 	}
 	b.blocks[block].Data = data
@@ -48,9 +51,11 @@ func (b *Blockchain) corrupt(block int, data []byte) error {
 
 func TestSuccess(t *testing.T) {
 	b := newBlockChain()
-	if err := b.dumpBlockchain(t); err != nil {
+	r := &recorder{T: t}
+	if err := b.dumpBlockchain(r); err != nil {
 		t.Fatal(err)
 	}
+	golden(t, "success", r.out.Bytes())
 }
 
 func TestFailure(t *testing.T) {
@@ -58,7 +63,9 @@ func TestFailure(t *testing.T) {
 	if err := b.corrupt(1, []byte("Send 2 Decred to Alice")); err != nil {
 		t.Fatal(err)
 	}
-	if err := b.dumpBlockchain(t); err == nil {
+	r := &recorder{T: t}
+	if err := b.dumpBlockchain(r); err == nil {
 		t.Fatalf("Unexpected success")
 	}
+	golden(t, "failure", r.out.Bytes())
 }
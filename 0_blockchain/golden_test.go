@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// logger is the part of testing.T that is used to print results.
+type logger interface {
+	Log(args ...interface{})
+	Logf(format string, args ...interface{})
+}
+
+// recorder logs like testing.T and keeps a copy of everything that was
+// logged so that it can be compared against golden output.
+type recorder struct {
+	*testing.T
+	out bytes.Buffer
+}
+
+// Log logs args and records them.
+func (r *recorder) Log(args ...interface{}) {
+	r.Helper()
+	r.T.Log(args...)
+	r.record(fmt.Sprint(args...))
+}
+
+// Logf logs a formatted line and records it.
+func (r *recorder) Logf(format string, args ...interface{}) {
+	r.Helper()
+	r.T.Logf(format, args...)
+	r.record(fmt.Sprintf(format, args...))
+}
+
+// record appends line to the recorded output.
+func (r *recorder) record(line string) {
+	r.out.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+}
+
+// golden compares got against testdata/name.golden. Run the tests with
+// -update to rewrite the golden files after an intended change.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	filename := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("output differs from %v, got:\n%s", filename, got)
+	}
+}
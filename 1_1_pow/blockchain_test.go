@@ -7,8 +7,8 @@ import (
 	"time"
 )
 
-func (b Block) dump(t *testing.T) {
-	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0))
+func (b Block) dump(t logger) {
+	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0).UTC())
 	t.Logf("PreviousBlockHash: %x\n", b.PreviousBlockHash)
 	t.Logf("Hash             : %x\n", b.Hash)
 	t.Logf("Data             : %s\n", string(b.Data))
@@ -17,46 +17,64 @@ func (b Block) dump(t *testing.T) {
 
 func TestMiningPool(t *testing.T) {
 	// May have to play with the increment value on a fast machine.
-	mp, err := NewMiningPool(100000)
+	clock := NewManualClock(time.Unix(1538345873, 0))
+	mp, err := NewMiningPool(clock, 100000)
 	if err != nil {
 		t.Fatal(err)
 	}
+	r := &recorder{T: t}
+
+	// Hand out work in miner order so that every miner gets the same
+	// range on every run.
+	maxWorkers := 10 // increse for more racing
+	type work struct {
+		start, end uint64
+		blk        *Block
+		err        error
+	}
+	works := make([]work, maxWorkers)
+	for x := range works {
+		works[x].start, works[x].end, works[x].blk = mp.GetWork(x)
+	}
 
 	// Start racing miners.
 	var wg sync.WaitGroup
-	maxWorkers := 10 // increse for more racing
-	for x := 0; x < maxWorkers; x++ {
-		minerID := x
+	for x := range works {
+		w := &works[x]
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			start, end, blk := mp.GetWork(minerID) // Obtain work
-			err := blk.Mine(Difficulty, start, end)
-			if err != nil {
-				t.Logf("%v %v %v: %v", minerID, start, end, err)
-				return
-			}
-
-			err = mp.CommitWork(blk) // Send to pool
-			if err != nil {
-				t.Logf("commit: %v %v %v: %v",
-					minerID, start, end, err)
-				return
-			}
-			t.Logf("%v %v %v: nonce %v", minerID, start, end,
-				blk.Nonce)
+			w.err = w.blk.Mine(Difficulty, w.start, w.end)
 		}()
 	}
-
 	wg.Wait()
 
+	// Commit in miner order instead of in the order the goroutines
+	// happened to finish. The first solution wins and all others no
+	// longer link to the tip of the chain.
+	for minerID, w := range works {
+		if w.err != nil {
+			r.Logf("%v %v %v: %v", minerID, w.start, w.end, w.err)
+			continue
+		}
+		err = mp.CommitWork(w.blk) // Send to pool
+		if err != nil {
+			r.Logf("commit: %v %v %v: %v", minerID, w.start, w.end,
+				err)
+			continue
+		}
+		r.Logf("%v %v %v: nonce %v", minerID, w.start, w.end,
+			w.blk.Nonce)
+	}
+
 	// Dump blockchain
 	for i := 0; i < mp.blockchain.Len(); i++ {
-		t.Log(strings.Repeat("=", 80))
+		r.Log(strings.Repeat("=", 80))
 		blk, err := mp.blockchain.Block(i)
 		if err != nil {
 			t.Fatal(err)
 		}
-		blk.dump(t)
+		blk.dump(r)
 	}
+	golden(t, "miningpool", r.out.Bytes())
 }
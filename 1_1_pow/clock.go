@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Block timestamps come from a Clock instead of calling time.Now directly.
+// Tests and reproducible runs use a ManualClock that only moves when told to.
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is a Clock that returns the system time.
+type systemClock struct{}
+
+// Now returns the system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock that returns the system time.
+var SystemClock Clock = systemClock{}
+
+// ManualClock is a Clock that only advances when it is set or advanced
+// explicitly. It is safe for concurrent use.
+type ManualClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a ManualClock that is set to now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// Set sets the clock to now.
+func (c *ManualClock) Set(now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -48,9 +48,9 @@ func (p *MiningPool) CommitWork(blk *Block) error {
 	return nil
 }
 
-// NewMiningPool returns a miningpool context.
-func NewMiningPool(increment uint64) (*MiningPool, error) {
-	b, err := NewBlockChain([]byte("Decred is money!"))
+// NewMiningPool returns a miningpool context. Blocks are timestamped by clock.
+func NewMiningPool(clock Clock, increment uint64) (*MiningPool, error) {
+	b, err := NewBlockChain(clock, []byte("Decred is money!"))
 	if err != nil {
 		return nil, err
 	}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"time"
 )
 
 const Difficulty = 16 // Static difficulty for PoW calculation
@@ -32,9 +31,10 @@ type Block struct {
 	Nonce             uint64 // Nonce used to calculate Hash
 }
 
-// NewBlock returns a block that is linked to previousBlockHash.
-func NewBlock(data, previousBlockHash []byte) Block {
-	timestamp := time.Now().Unix()
+// NewBlock returns a block that is linked to previousBlockHash and is
+// timestamped by clock.
+func NewBlock(clock Clock, data, previousBlockHash []byte) Block {
+	timestamp := clock.Now().Unix()
 	return Block{
 		Timestamp:         timestamp,
 		Data:              data,
@@ -74,6 +74,7 @@ func (b *Block) Mine(difficulty uint) error {
 
 // Blockchain is the blockchain context that houses an array of blocks.
 type Blockchain struct {
+	clock  Clock // Source of block timestamps
 	blocks []*Block
 }
 
@@ -107,13 +108,13 @@ func (b *Blockchain) PrepareBlock(data []byte) *Block {
 	} else {
 		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
 	}
-	blk := NewBlock(data, previousBlockHash)
+	blk := NewBlock(b.clock, data, previousBlockHash)
 	return &blk
 }
 
 // Block returns a copy of the block at the specified block height.
 func (b Blockchain) Block(block int) (Block, error) {
-	if block > len(b.blocks) {
+	if block < 0 || block >= len(b.blocks) {
 		return Block{}, fmt.Errorf("invalid block: %v", block)
 	}
 	return *b.blocks[block], nil
@@ -124,9 +125,10 @@ func (b Blockchain) Len() int {
 	return len(b.blocks)
 }
 
-// NewBlockChain returns a blockchain context that has a genesis block.
-func NewBlockChain(data []byte) (*Blockchain, error) {
-	b := &Blockchain{}
+// NewBlockChain returns a blockchain context that has a genesis block. All
+// blocks are timestamped by clock.
+func NewBlockChain(clock Clock, data []byte) (*Blockchain, error) {
+	b := &Blockchain{clock: clock}
 	blk := b.PrepareBlock(data)
 	err := blk.Mine(Difficulty)
 	if err != nil {
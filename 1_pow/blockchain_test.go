@@ -7,8 +7,8 @@ import (
 	"time"
 )
 
-func (b Block) dump(t *testing.T) {
-	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0))
+func (b Block) dump(t logger) {
+	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0).UTC())
 	t.Logf("PreviousBlockHash: %x\n", b.PreviousBlockHash)
 	t.Logf("Hash             : %x\n", b.Hash)
 	t.Logf("Data             : %s\n", string(b.Data))
@@ -16,7 +16,7 @@ func (b Block) dump(t *testing.T) {
 }
 
 func (b *Blockchain) corrupt(block int, data []byte) error {
-	if block > len(b.blocks) {
+	if block < 0 || block >= len(b.blocks) {
 		return fmt.Errorf("invalid block: %v", block)
 	}
 	b.blocks[block].Data = data
@@ -24,11 +24,14 @@ func (b *Blockchain) corrupt(block int, data []byte) error {
 }
 
 func TestBlockChain(t *testing.T) {
-	b, err := NewBlockChain([]byte("Decred is money!"))
+	clock := NewManualClock(time.Unix(1538345873, 0))
+	b, err := NewBlockChain(clock, []byte("Decred is money!"))
 	if err != nil {
 		t.Fatal(err)
 	}
+	r := &recorder{T: t}
 
+	clock.Advance(10 * time.Minute)
 	blk := b.PrepareBlock([]byte("Send 1 Decred to Alice"))
 	err = blk.Mine(Difficulty)
 	if err != nil {
@@ -39,6 +42,7 @@ func TestBlockChain(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	clock.Advance(10 * time.Minute)
 	blk = b.PrepareBlock([]byte("Send 2 Decred to Bob"))
 	err = blk.Mine(Difficulty)
 	if err != nil {
@@ -50,12 +54,12 @@ func TestBlockChain(t *testing.T) {
 	}
 
 	for i := 0; i < b.Len(); i++ {
-		t.Log(strings.Repeat("=", 80))
+		r.Log(strings.Repeat("=", 80))
 		blk, err := b.Block(i)
 		if err != nil {
 			t.Fatal(err)
 		}
-		blk.dump(t)
+		blk.dump(r)
 		if !blk.Verify() {
 			t.Fatalf("corrupt")
 		}
@@ -66,15 +70,16 @@ func TestBlockChain(t *testing.T) {
 		t.Fatal(err)
 	}
 	for i := 0; i < b.Len(); i++ {
-		t.Log(strings.Repeat("=", 80))
+		r.Log(strings.Repeat("=", 80))
 		blk, err := b.Block(i)
 		if err != nil {
 			t.Fatal(err)
 		}
-		blk.dump(t)
+		blk.dump(r)
 		if !blk.Verify() && i == 1 {
-			t.Logf("Block 1 corrupt")
-			return
+			r.Logf("Block 1 corrupt")
+			break
 		}
 	}
+	golden(t, "blockchain", r.out.Bytes())
 }
@@ -3,20 +3,15 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"math/rand"
 	"testing"
 )
 
-func assertPanic(t *testing.T, f func()) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("The code did not panic")
-		}
-	}()
-	f()
-}
-
 func TestPK(t *testing.T) {
-	key, err := NewKey()
+	entropy := rand.New(rand.NewSource(1))
+	key, err := NewKey(entropy)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -38,27 +33,123 @@ func TestPK(t *testing.T) {
 	if pk.Verify(hash[:], signature) {
 		t.Fatalf("verify succeeded")
 	}
+
+	// The same seed creates the same key.
+	other, err := NewKey(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(other.Public(), key.Public()) {
+		t.Fatalf("seeded keys differ")
+	}
+}
+
+func TestSignRFC6979(t *testing.T) {
+	// P-256 with SHA-256 vectors from RFC 6979 appendix A.2.5.
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d693"+
+		"4e50c3db36e89b127b8a622b120f6721", 16)
+	key := newPrivateKey(d)
+	tests := []struct {
+		message   string
+		signature string
+	}{
+		{
+			"sample",
+			"efd48b2aacb6a8fd1140dd9cd45e81d6" +
+				"9d2c877b56aaf991c34d0ea84eaf3716" +
+				"f7cb1c942d657c41d436c7a1b6e29f65" +
+				"f3e900dbb9aff4064dc4ab2f843acda8",
+		},
+		{
+			"test",
+			"f1abb023518351cd71d881567b1ea663" +
+				"ed3efcf6c5132b354f28d3b0b7d38367" +
+				"019f4113742a2b14bd25926b49c64915" +
+				"5f267e60d3814b4c0cc84250e46f0083",
+		},
+	}
+	for _, test := range tests {
+		hash := sha256.Sum256([]byte(test.message))
+		signature, err := key.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(signature) != test.signature {
+			t.Fatalf("%v: got %x want %v", test.message, signature,
+				test.signature)
+		}
+		pk := NewPublicKey(key.Public())
+		if !pk.Verify(hash[:], signature) {
+			t.Fatalf("%v: verify failed", test.message)
+		}
+	}
+}
+
+func TestShortCoordinates(t *testing.T) {
+	// The X coordinate of the public key of 379 and the s value of the
+	// signature of "114" with key 1 have a leading zero byte. Both must
+	// still be serialized as 32 bytes so that they can be split in half.
+	key := newPrivateKey(big.NewInt(379))
+	if len(key.PublicKey.X.Bytes()) != coordinateSize-1 {
+		t.Fatalf("X is %v bytes", len(key.PublicKey.X.Bytes()))
+	}
+	pub := key.Public()
+	if len(pub) != 2*coordinateSize {
+		t.Fatalf("public key is %v bytes", len(pub))
+	}
+	pk := NewPublicKey(pub)
+	if pk.X.Cmp(key.PublicKey.X) != 0 || pk.Y.Cmp(key.PublicKey.Y) != 0 {
+		t.Fatalf("public key does not round trip")
+	}
+	if !bytes.Equal(pk.Key(), pub) {
+		t.Fatalf("public key hashed in another form")
+	}
+	hash := sha256.Sum256([]byte("ok"))
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pk.Verify(hash[:], signature) {
+		t.Fatalf("verify failed")
+	}
+
+	key = newPrivateKey(big.NewInt(1))
+	hash = sha256.Sum256([]byte("114"))
+	signature, err = key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) != 2*coordinateSize ||
+		signature[coordinateSize] != 0 {
+		t.Fatalf("signature without short s: %x", signature)
+	}
+	if !NewPublicKey(key.Public()).Verify(hash[:], signature) {
+		t.Fatalf("verify of short s failed")
+	}
 }
 
 func TestAddress(t *testing.T) {
-	key, err := NewKey()
+	entropy := rand.New(rand.NewSource(1))
+	key, err := NewKey(entropy)
 	if err != nil {
 		t.Fatal(err)
 	}
 	pk := NewPublicKey(key.Public())
-	a := pk.Address()
+	a := pk.Address(&MainNetParams)
 	t.Logf("Version   : %v", a.Version)
 	t.Logf("PubKeyHash: %x", a.PubKeyHash)
 	t.Logf("Checksum  : %x", a.Checksum)
+	t.Logf("Network   : %v", a.Net)
 	t.Logf("Address  : %v", a)
 
-	aa, err := NewAddress(a.String())
+	aa, err := NewAddress(a.String(), &MainNetParams)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Logf("Version   : %v", aa.Version)
 	t.Logf("PubKeyHash: %x", aa.PubKeyHash)
 	t.Logf("Checksum  : %x", aa.Checksum)
+	t.Logf("Network   : %v", aa.Net)
 	t.Logf("Address  : %v", aa)
 
 	if aa.String() != a.String() {
@@ -67,32 +158,82 @@ func TestAddress(t *testing.T) {
 }
 
 func TestAddressCorrupt(t *testing.T) {
-	key, err := NewKey()
+	entropy := rand.New(rand.NewSource(1))
+	key, err := NewKey(entropy)
 	if err != nil {
 		t.Fatal(err)
 	}
 	pk := NewPublicKey(key.Public())
-	a := pk.Address()
-	aa, err := NewAddress(a.String())
+	a := pk.Address(&MainNetParams)
+	aa, err := NewAddress(a.String(), &MainNetParams)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	assertPanic(t, func() {
-		// Corrupt Address
-		aa.PubKeyHash[0] = ^aa.PubKeyHash[0]
-		if aa.String() == a.String() {
-			t.Fatalf("stringers match")
-		}
-	})
+	// Corrupt Address
+	aa.PubKeyHash[0] = ^aa.PubKeyHash[0]
+	if err := aa.Verify(); err != ErrChecksum {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, err := aa.Encode(FormatBase58); err != ErrChecksum {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if aa.String() == a.String() {
+		t.Fatalf("stringers match")
+	}
+	if _, err := NewAddress(aa.String(), &MainNetParams); err == nil {
+		t.Fatalf("corrupt address printed as a valid address")
+	}
 
 	// Fix checksum and try again
 	aa.Checksum = checksum(append([]byte{aa.Version}, aa.PubKeyHash...))
 	if bytes.Equal(aa.Checksum, a.Checksum) {
 		t.Fatal("checksums should not be equal")
 	}
+	if err := aa.Verify(); err != nil {
+		t.Fatal(err)
+	}
 
 	if aa.String() == a.String() {
 		t.Fatalf("stringers match")
 	}
 }
+
+func TestAddressNetworks(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := NewPublicKey(key.Public())
+	for _, net := range networks {
+		a := pk.Address(net)
+		t.Logf("%-8v: %v", net, a)
+
+		aa, err := DecodeAddress(a.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aa.Net != net {
+			t.Fatalf("invalid network: got %v want %v", aa.Net, net)
+		}
+
+		for _, other := range networks {
+			_, err := NewAddress(a.String(), other)
+			if other == net && err != nil {
+				t.Fatal(err)
+			}
+			if other != net && err == nil {
+				t.Fatalf("%v address accepted on %v", net, other)
+			}
+		}
+	}
+
+	// Unknown version
+	a := pk.Address(&MainNetParams)
+	a.Version = 0xff
+	a.Checksum = checksum(append([]byte{a.Version}, a.PubKeyHash...))
+	if _, err := DecodeAddress(a.String()); err == nil {
+		t.Fatal("unknown version accepted")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeErr(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []byte
+		pos int // position of invalid character, -1 if valid
+	}{
+		{"", []byte{}, -1},
+		{"1", []byte{0}, -1},
+		{"111", []byte{0, 0, 0}, -1},
+		{"2g", []byte("a"), -1},
+		{"1112", []byte{0, 0, 0, 1}, -1},
+		{"0", nil, 0},
+		{"2gO", nil, 2},
+		{"l1", nil, 0},
+		{"1I1", nil, 1},
+		{"1 ", nil, 1},
+	}
+	for _, test := range tests {
+		out, err := DecodeErr(test.in)
+		if test.pos == -1 {
+			if err != nil {
+				t.Fatalf("%q: %v", test.in, err)
+			}
+			if !bytes.Equal(out, test.out) {
+				t.Fatalf("%q: got %x want %x", test.in, out,
+					test.out)
+			}
+			continue
+		}
+		e, ok := err.(InvalidCharacterError)
+		if !ok {
+			t.Fatalf("%q: invalid error: %v", test.in, err)
+		}
+		if e.Position != test.pos || e.Char != test.in[test.pos] {
+			t.Fatalf("%q: invalid error: %v", test.in, err)
+		}
+		t.Logf("%q: %v", test.in, err)
+
+		// Legacy behavior
+		if out := Decode(test.in); len(out) != 0 {
+			t.Fatalf("%q: expected empty slice", test.in)
+		}
+	}
+}
+
+func TestCheckEncode(t *testing.T) {
+	payload := []byte("Decred is money!")
+	for _, version := range []byte{0, 0x3f, 0x6f, 0xff} {
+		s := CheckEncode(payload, version)
+		p, v, err := CheckDecode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != version || !bytes.Equal(p, payload) {
+			t.Fatalf("round trip failed: %v %x", v, p)
+		}
+	}
+
+	s := CheckEncode(payload, 0)
+
+	// Corrupt checksum
+	b := []byte(s)
+	if b[len(b)-1] == '2' {
+		b[len(b)-1] = '3'
+	} else {
+		b[len(b)-1] = '2'
+	}
+	if _, _, err := CheckDecode(string(b)); err != ErrChecksum {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	// Too short
+	if _, _, err := CheckDecode(Encode([]byte{1, 2, 3, 4})); err !=
+		ErrInvalidLength {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, _, err := CheckDecode(""); err != ErrInvalidLength {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	// Invalid character
+	if _, _, err := CheckDecode(s + "0"); err == nil {
+		t.Fatalf("invalid character accepted")
+	} else if _, ok := err.(InvalidCharacterError); !ok {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func TestDecodeAddressErrors(t *testing.T) {
+	pkh := make([]byte, 20)
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{CheckEncode(pkh, 0xff), InvalidVersionError{Version: 0xff}},
+		{CheckEncode(pkh[:19], 0), ErrInvalidLength},
+		{CheckEncode(nil, 0), ErrInvalidLength},
+		{"", ErrInvalidLength},
+		{"1O", InvalidCharacterError{Position: 1, Char: 'O'}},
+	}
+	for _, test := range tests {
+		_, err := DecodeAddress(test.in)
+		if err != test.err {
+			t.Fatalf("%q: got %v want %v", test.in, err, test.err)
+		}
+	}
+}
+
+func FuzzEncode(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0})
+	f.Add([]byte{0, 0, 1})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Add(append([]byte{0}, make([]byte, 24)...))
+	f.Add([]byte("Decred is money!"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		got, want := Encode(b), encodeBig(b)
+		if got != want {
+			t.Fatalf("%x: got %v want %v", b, got, want)
+		}
+		d, err := DecodeErr(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(d, b) {
+			t.Fatalf("round trip got %x want %x", d, b)
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add("")
+	f.Add("1")
+	f.Add("11112")
+	f.Add("zzzzzzzzzzzzzzzzzzzzz")
+	f.Add("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	f.Add("2gO")
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := DecodeErr(s)
+		want, wantErr := decodeBig(s)
+		if err != wantErr {
+			t.Fatalf("%q: got %v want %v", s, err, wantErr)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%q: got %x want %x", s, got, want)
+		}
+		if !bytes.Equal(Decode(s), []byte(string(want))) {
+			t.Fatalf("%q: decode mismatch", s)
+		}
+	})
+}
+
+// benchmarkPayload returns a 25 byte version+pub key hash+checksum payload.
+func benchmarkPayload() []byte {
+	b := make([]byte, 25)
+	for i := 1; i < len(b); i++ {
+		b[i] = byte(i * 37)
+	}
+	return b
+}
+
+func BenchmarkEncode(b *testing.B) {
+	p := benchmarkPayload()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Encode(p)
+	}
+}
+
+func BenchmarkEncodeBig(b *testing.B) {
+	p := benchmarkPayload()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encodeBig(p)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	s := Encode(benchmarkPayload())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Decode(s)
+	}
+}
+
+func BenchmarkDecodeBig(b *testing.B) {
+	s := Encode(benchmarkPayload())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		decodeBig(s)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/golangcrypto/ripemd160"
+)
+
+// Test vectors from BIP173 and BIP350.
+var (
+	validBech32 = []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	}
+
+	validBech32m = []string{
+		"A1LQFN3A",
+		"a1lqfn3a",
+		"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6",
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+		"11llllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllludsr8",
+		"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+		"?1v759aa",
+	}
+
+	invalidBech32 = []string{
+		"\x201nwldj5", // HRP character out of range
+		"\x7f1axkwrx", // HRP character out of range
+		"\x801eym55h", // HRP character out of range
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", // too long
+		"pzry9x0s0muk",  // no separator
+		"1pzry9x0s0muk", // empty HRP
+		"x1b4n0q5v",     // invalid data character
+		"li1dgmt3",      // checksum too short
+		"de1lg7wt\xff",  // invalid character in checksum
+		"A1G7SGD8",      // checksum calculated with uppercase HRP
+		"10a06t8",       // empty HRP
+		"1qzzfhee",      // empty HRP
+	}
+
+	invalidBech32m = []string{
+		"\x201xj0phk", // HRP character out of range
+		"\x7f1g6xzxy", // HRP character out of range
+		"\x801vctc34", // HRP character out of range
+		"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4", // too long
+		"qyrz8wqd2c9m",  // no separator
+		"1qyrz8wqd2c9m", // empty HRP
+		"y1b0jsk6g",     // invalid data character
+		"lt1igcx5c0",    // invalid data character
+		"in1muywd",      // checksum too short
+		"mm1crxm3i",     // invalid character in checksum
+		"au1s5cgom",     // invalid character in checksum
+		"M1VUXWEZ",      // checksum calculated with uppercase HRP
+		"16plkw9",       // empty HRP
+		"1p2gdwpf",      // empty HRP
+	}
+)
+
+func testBech32Valid(t *testing.T, vectors []string, variant Bech32Variant) {
+	for _, s := range vectors {
+		hrp, data, v, err := Bech32Decode(s)
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+		if v != variant {
+			t.Fatalf("%v: invalid variant got %v want %v", s, v,
+				variant)
+		}
+
+		// Round trip yields the lowercase form.
+		e, err := Bech32Encode(hrp, data, variant)
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+		if e != strings.ToLower(s) {
+			t.Fatalf("round trip got %v want %v", e,
+				strings.ToLower(s))
+		}
+
+		// Flip one character in the data part.
+		b := []byte(strings.ToLower(s))
+		i := strings.LastIndexByte(s, bech32Separator) + 1
+		b[i] = charset[(strings.IndexByte(charset, b[i])+1)%32]
+		if _, _, _, err := Bech32Decode(string(b)); err == nil {
+			t.Fatalf("%v: corruption not detected", string(b))
+		}
+	}
+}
+
+func testBech32Invalid(t *testing.T, vectors []string) {
+	for _, s := range vectors {
+		if _, _, _, err := Bech32Decode(s); err == nil {
+			t.Fatalf("%q: unexpected success", s)
+		} else {
+			t.Logf("%q: %v", s, err)
+		}
+	}
+}
+
+func TestBech32(t *testing.T) {
+	testBech32Valid(t, validBech32, Bech32)
+	testBech32Invalid(t, invalidBech32)
+}
+
+func TestBech32m(t *testing.T) {
+	testBech32Valid(t, validBech32m, Bech32m)
+	testBech32Invalid(t, invalidBech32m)
+}
+
+func TestAddressBech32(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := NewPublicKey(key.Public())
+	for _, net := range networks {
+		a := pk.Address(net)
+		for _, format := range []AddressFormat{FormatBase58,
+			FormatBech32, FormatBech32m} {
+
+			s, err := a.Encode(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("%-8v %-8v: %v", net, format, s)
+
+			if format != FormatBase58 && s != strings.ToLower(s) {
+				t.Fatalf("not lowercase: %v", s)
+			}
+
+			aa, err := NewAddress(s, net)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aa.Format != format {
+				t.Fatalf("invalid format got %v want %v",
+					aa.Format, format)
+			}
+			if !bytes.Equal(aa.PubKeyHash, a.PubKeyHash) {
+				t.Fatalf("pub key hash mismatch")
+			}
+			if aa.String() != a.String() {
+				t.Fatalf("stringers don't match")
+			}
+
+			// Uppercase is accepted as well.
+			if format != FormatBase58 {
+				_, err := NewAddress(strings.ToUpper(s), net)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	// Corrupt a single character.
+	s, err := pk.Address(&MainNetParams).Encode(FormatBech32m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := []byte(s)
+	b[len(b)-1] = charset[(strings.IndexByte(charset, b[len(b)-1])+1)%32]
+	if _, err := DecodeAddress(string(b)); err == nil {
+		t.Fatalf("corrupt address accepted")
+	}
+}
+
+func TestAddressBase58Fallback(t *testing.T) {
+	// A network with version 1 has base58 addresses that may start with
+	// "ec1", which looks like a mainnet bech32 address.
+	net := &Params{
+		Name:             "fallback",
+		PubKeyHashAddrID: 0x01,
+		Bech32HRP:        "fb",
+	}
+	defer func(n []*Params) { networks = n }(networks)
+	networks = append(networks, net)
+
+	hash := sha256.Sum256([]byte("3411"))
+	a := &Address{
+		Version:    net.PubKeyHashAddrID,
+		PubKeyHash: hash[:ripemd160.Size],
+		Checksum: checksum(append([]byte{net.PubKeyHashAddrID},
+			hash[:ripemd160.Size]...)),
+	}
+	s := a.String()
+	t.Logf("%v", s)
+	if !isBech32Address(s) {
+		t.Fatalf("%v does not look like bech32", s)
+	}
+	aa, err := DecodeAddress(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aa.Net != net || aa.Format != FormatBase58 {
+		t.Fatalf("decoded as %v on %v", aa.Format, aa.Net)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func TestSignMessage(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	message := []byte("Send 1 Decred to Alice")
+	for i := 0; i < 32; i++ {
+		key, err := NewKey(entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := NewPublicKey(key.Public()).Address(&MainNetParams)
+		signature, err := key.SignMessage(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			t.Logf("Address  : %v", a)
+			t.Logf("Message  : %s", message)
+			t.Logf("Signature: %x", signature)
+		}
+
+		// Verify with nothing but the decoded address.
+		aa, err := NewAddress(a.String(), &MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyMessage(aa, message, signature) {
+			t.Fatalf("verify failed")
+		}
+
+		pk, err := RecoverPublicKey(MessageHash(message), signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pk.Key(), key.Public()) {
+			t.Fatalf("recovered invalid public key")
+		}
+	}
+}
+
+func TestVerifyMessageFailure(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	message := []byte("Send 1 Decred to Alice")
+	alice, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewPublicKey(alice.Public()).Address(&MainNetParams)
+	b := NewPublicKey(bob.Public()).Address(&MainNetParams)
+	signature, err := alice.SignMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wrong address
+	if VerifyMessage(b, message, signature) {
+		t.Fatalf("verified with wrong address")
+	}
+
+	// Modified message
+	if VerifyMessage(a, []byte("Send 2 Decred to Alice"), signature) {
+		t.Fatalf("verified modified message")
+	}
+
+	// Corrupt signature
+	for _, i := range []int{0, 1, 40, MessageSignatureSize - 1} {
+		s := append([]byte{}, signature...)
+		s[i] ^= 0x01
+		if VerifyMessage(a, message, s) {
+			t.Fatalf("verified corrupt signature at %v", i)
+		}
+	}
+	if VerifyMessage(a, message, signature[1:]) {
+		t.Fatalf("verified short signature")
+	}
+
+	// Signatures over the raw hash are not message signatures.
+	raw, err := alice.Sign(MessageHash(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyMessage(a, message, raw) {
+		t.Fatalf("verified raw signature")
+	}
+}
+
+func TestMessageHash(t *testing.T) {
+	// The magic prefix separates message hashes from plain hashes.
+	h0 := MessageHash([]byte("Hello world!"))
+	h1 := MessageHash([]byte("Hello world?"))
+	if bytes.Equal(h0, h1) {
+		t.Fatalf("hash collision")
+	}
+	t.Logf("Hash: %v", hex.EncodeToString(h0))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/golangcrypto/pbkdf2"
+)
+
+// A mnemonic (BIP39) encodes entropy as a list of words from a word list. Each
+// word encodes 11 bits. The entropy is followed by a checksum of
+// len(entropy)/32 bits which is taken from the sha256 of the entropy. A
+// mnemonic is converted into a 512 bit seed using PBKDF2 and the seed is in
+// turn used to deterministically derive private keys.
+
+const (
+	bitsPerWord = 11 // Number of bits encoded by a single word
+
+	minEntropyBits = 128 // Minimum entropy, 12 words
+	maxEntropyBits = 256 // Maximum entropy, 24 words
+
+	seedIterations = 2048 // PBKDF2 iterations
+	SeedSize       = 64   // Size of the seed in bytes
+)
+
+// wordIndex maps each English word to its index in the word list.
+var wordIndex = func() map[string]int {
+	m := make(map[string]int, len(englishWords))
+	for i, w := range englishWords {
+		m[w] = i
+	}
+	return m
+}()
+
+// validEntropyBits returns an error if bits is not a valid entropy size.
+func validEntropyBits(bits int) error {
+	if bits < minEntropyBits || bits > maxEntropyBits || bits%32 != 0 {
+		return fmt.Errorf("invalid entropy size: %v", bits)
+	}
+	return nil
+}
+
+// NewEntropy returns bits of random entropy. The size must be a multiple of 32
+// between 128 and 256.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := validEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := io.ReadFull(rand.Reader, entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic returns the English mnemonic of entropy.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := validEntropyBits(bits); err != nil {
+		return "", err
+	}
+
+	// Append the checksum bits to the entropy.
+	chkBits := uint(bits / 32)
+	chk := sha256.Sum256(entropy)
+	x := new(big.Int).SetBytes(entropy)
+	x.Lsh(x, chkBits)
+	x.Or(x, big.NewInt(int64(chk[0]>>(8-chkBits))))
+
+	// Peel off 11 bits per word, last word first.
+	words := make([]string, (bits+int(chkBits))/bitsPerWord)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	idx := new(big.Int)
+	for i := len(words) - 1; i >= 0; i-- {
+		idx.And(x, mask)
+		words[i] = englishWords[idx.Int64()]
+		x.Rsh(x, bitsPerWord)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic decodes mnemonic and verifies its checksum. It returns
+// the entropy that was used to create the mnemonic.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	total := len(words) * bitsPerWord
+	chkBits := total / 33
+	bits := total - chkBits
+	if len(words)%3 != 0 || validEntropyBits(bits) != nil {
+		return nil, fmt.Errorf("invalid number of words: %v",
+			len(words))
+	}
+
+	x := new(big.Int)
+	for i, w := range words {
+		idx, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("invalid word at position %v: %v",
+				i, w)
+		}
+		x.Lsh(x, bitsPerWord)
+		x.Or(x, big.NewInt(int64(idx)))
+	}
+
+	// Split checksum and entropy.
+	chk := byte(x.Uint64() & (1<<uint(chkBits) - 1))
+	x.Rsh(x, uint(chkBits))
+	entropy := make([]byte, bits/8)
+	x.FillBytes(entropy)
+
+	expected := sha256.Sum256(entropy)
+	if expected[0]>>uint(8-chkBits) != chk {
+		return nil, fmt.Errorf("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// NewSeed verifies mnemonic and returns the 512 bit seed that is derived from
+// it and an optional passphrase. The passphrase is expected to be in Unicode
+// NFKD form.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase),
+		seedIterations, SeedSize, sha512.New), nil
+}
+
+// NewKeyFromSeed is the deterministic counterpart of NewKey. It derives the
+// private key with the provided index from seed. Restoring a seed from its
+// mnemonic therefore restores all keys that were derived from it.
+//
+// The private scalar is the first half of HMAC-SHA512(seed, index||counter).
+// The counter is incremented in the unlikely event that the scalar is zero or
+// not smaller than the curve order.
+func NewKeyFromSeed(seed []byte, index uint32) (*PrivateKey, error) {
+	if len(seed) < 16 {
+		return nil, fmt.Errorf("seed too short")
+	}
+	n := elliptic.P256().Params().N
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg, index)
+	for counter := uint32(0); ; counter++ {
+		binary.BigEndian.PutUint32(msg[4:], counter)
+		mac := hmac.New(sha512.New, seed)
+		mac.Write(msg)
+		d := new(big.Int).SetBytes(mac.Sum(nil)[:32])
+		if d.Sign() == 0 || d.Cmp(n) >= 0 {
+			continue
+		}
+		return newPrivateKey(d), nil
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A vanity address is an address that starts with a chosen prefix. There is
+// no way to pick the address of a key, so the only way to find one is to
+// generate keys until an address matches. Every additional base58 character
+// makes the search 58 times harder. The search is spread across a number of
+// workers much like miners in a mining pool.
+
+// defaultProgressInterval is the default interval between progress reports.
+const defaultProgressInterval = time.Second
+
+// VanityProgress is periodically reported while searching.
+type VanityProgress struct {
+	Attempts uint64        // Number of keys generated so far
+	Elapsed  time.Duration // Time since the search started
+	Expected float64       // Expected number of attempts
+}
+
+// Rate returns the number of attempts per second.
+func (p VanityProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Attempts) / p.Elapsed.Seconds()
+}
+
+// VanityResult is the key that was found by a vanity search.
+type VanityResult struct {
+	Key      *PrivateKey // Private key of the vanity address
+	Address  *Address    // Vanity address
+	Attempts uint64      // Number of keys generated by all workers
+}
+
+// Vanity is the context of a vanity address search.
+type Vanity struct {
+	ProgressInterval time.Duration        // Time between progress reports
+	Progress         func(VanityProgress) // Progress callback, may be nil
+
+	net             *Params // Network of the address
+	prefix          string  // Requested prefix
+	caseInsensitive bool    // Ignore case while matching
+	expected        float64 // Expected number of attempts
+	attempts        uint64  // Attempts, accessed atomically
+}
+
+// leadingCharacter returns the first character that all base58 addresses of
+// net share or 0 if the first character varies.
+func leadingCharacter(net *Params) byte {
+	version := net.PubKeyHashAddrID
+	lo := CheckEncode(make([]byte, 20), version)
+	hi := CheckEncode(bytes.Repeat([]byte{0xff}, 20), version)
+	if lo[0] == hi[0] {
+		return lo[0]
+	}
+	return 0
+}
+
+// otherCase returns c in the opposite case or 0 if c is not a letter.
+func otherCase(c byte) byte {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 'A'
+	case c >= 'A' && c <= 'Z':
+		return c - 'A' + 'a'
+	}
+	return 0
+}
+
+// ExpectedAttempts returns the expected number of keys that must be generated
+// to find an address for net that starts with prefix. It assumes that every
+// character after the network specific leading character is uniformly
+// distributed, which is a good approximation for short prefixes. When
+// matching case insensitively a letter that exists in both cases in the
+// alphabet is twice as likely to match.
+func ExpectedAttempts(net *Params, prefix string,
+	caseInsensitive bool) float64 {
+
+	if len(prefix) > 0 && prefix[0] == leadingCharacter(net) {
+		prefix = prefix[1:]
+	}
+	expected := 1.0
+	for i := 0; i < len(prefix); i++ {
+		matches := 0.0
+		for _, c := range []byte{prefix[i], otherCase(prefix[i])} {
+			if c != 0 && b58[c] != 255 {
+				matches++
+			}
+			if !caseInsensitive {
+				break
+			}
+		}
+		if matches == 0 {
+			return math.Inf(1)
+		}
+		expected *= float64(len(alphabet)) / matches
+	}
+	return expected
+}
+
+// NewVanity returns a vanity address search context for addresses of network
+// net that start with prefix.
+func NewVanity(net *Params, prefix string, caseInsensitive bool) (*Vanity,
+	error) {
+
+	if len(prefix) == 0 {
+		return nil, fmt.Errorf("empty prefix")
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if b58[c] != 255 {
+			continue
+		}
+		o := otherCase(c)
+		if caseInsensitive && o != 0 && b58[o] != 255 {
+			continue
+		}
+		return nil, InvalidCharacterError{Position: i, Char: c}
+	}
+	if l := leadingCharacter(net); l != 0 && prefix[0] != l {
+		return nil, fmt.Errorf("%v addresses start with %q", net, l)
+	}
+	expected := ExpectedAttempts(net, prefix, caseInsensitive)
+	return &Vanity{
+		ProgressInterval: defaultProgressInterval,
+		net:              net,
+		prefix:           prefix,
+		caseInsensitive:  caseInsensitive,
+		expected:         expected,
+	}, nil
+}
+
+// Expected returns the expected number of attempts.
+func (v *Vanity) Expected() float64 {
+	return v.expected
+}
+
+// match returns true if address starts with the requested prefix.
+func (v *Vanity) match(address string) bool {
+	if len(address) < len(v.prefix) {
+		return false
+	}
+	if v.caseInsensitive {
+		return strings.EqualFold(address[:len(v.prefix)], v.prefix)
+	}
+	return address[:len(v.prefix)] == v.prefix
+}
+
+// worker generates keys until a matching address is found or ctx is done.
+func (v *Vanity) worker(ctx context.Context, found chan<- *VanityResult,
+	errc chan<- error) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		key, err := NewKey(rand.Reader)
+		if err != nil {
+			errc <- err
+			return
+		}
+		atomic.AddUint64(&v.attempts, 1)
+		a := NewPublicKey(key.Public()).Address(v.net)
+		if !v.match(a.String()) {
+			continue
+		}
+		select {
+		case found <- &VanityResult{Key: key, Address: a}:
+		case <-ctx.Done():
+		}
+		return
+	}
+}
+
+// Search runs the search on workers goroutines until an address is found or
+// ctx is canceled.
+func (v *Vanity) Search(ctx context.Context, workers int) (*VanityResult,
+	error) {
+
+	if workers < 1 {
+		return nil, fmt.Errorf("invalid number of workers: %v", workers)
+	}
+
+	atomic.StoreUint64(&v.attempts, 0)
+	ctx, cancel := context.WithCancel(ctx)
+
+	// Start racing workers.
+	found := make(chan *VanityResult)
+	errc := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v.worker(ctx, found, errc)
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	start := time.Now()
+	progress := func() {
+		if v.Progress == nil {
+			return
+		}
+		v.Progress(VanityProgress{
+			Attempts: atomic.LoadUint64(&v.attempts),
+			Elapsed:  time.Since(start),
+			Expected: v.expected,
+		})
+	}
+
+	var tick <-chan time.Time
+	if v.Progress != nil && v.ProgressInterval > 0 {
+		ticker := time.NewTicker(v.ProgressInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case r := <-found:
+			progress()
+			r.Attempts = atomic.LoadUint64(&v.attempts)
+			return r, nil
+		case err := <-errc:
+			return nil, err
+		case <-tick:
+			progress()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpectedAttempts(t *testing.T) {
+	tests := []struct {
+		prefix          string
+		caseInsensitive bool
+		expected        float64
+	}{
+		{"1", false, 1},
+		{"1a", false, 58},
+		{"1a", true, 29},
+		{"1ab", false, 58 * 58},
+		{"1o", true, 58}, // no 'O' in alphabet
+		{"1L", true, 58}, // no 'l' in alphabet
+		{"1z", true, 29},
+	}
+	for _, test := range tests {
+		e := ExpectedAttempts(&MainNetParams, test.prefix,
+			test.caseInsensitive)
+		if e != test.expected {
+			t.Fatalf("%v %v: got %v want %v", test.prefix,
+				test.caseInsensitive, e, test.expected)
+		}
+	}
+}
+
+func TestNewVanityInvalid(t *testing.T) {
+	if _, err := NewVanity(&MainNetParams, "", false); err == nil {
+		t.Fatalf("empty prefix accepted")
+	}
+	if _, err := NewVanity(&MainNetParams, "1O", false); err == nil {
+		t.Fatalf("invalid character accepted")
+	}
+	if _, err := NewVanity(&MainNetParams, "1O", true); err != nil {
+		t.Fatalf("case insensitive prefix rejected: %v", err)
+	}
+	if _, err := NewVanity(&MainNetParams, "2a", false); err == nil {
+		t.Fatalf("impossible prefix accepted")
+	}
+}
+
+func TestVanity(t *testing.T) {
+	for _, test := range []struct {
+		prefix          string
+		caseInsensitive bool
+	}{
+		{"1e", false},
+		{"1E", true},
+	} {
+		v, err := NewVanity(&MainNetParams, test.prefix,
+			test.caseInsensitive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.ProgressInterval = 10 * time.Millisecond
+		v.Progress = func(p VanityProgress) {
+			t.Logf("attempts %v/%.0f %.0f/s", p.Attempts,
+				p.Expected, p.Rate())
+		}
+		r, err := v.Search(context.Background(), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Address  : %v", r.Address)
+		t.Logf("Attempts : %v expected %v", r.Attempts, v.Expected())
+
+		a := r.Address.String()
+		if test.caseInsensitive {
+			a = strings.ToUpper(a)
+		}
+		if !strings.HasPrefix(a, test.prefix) {
+			t.Fatalf("invalid address: %v", r.Address)
+		}
+		pk := NewPublicKey(r.Key.Public())
+		if pk.Address(&MainNetParams).String() != r.Address.String() {
+			t.Fatalf("key does not match address")
+		}
+	}
+}
+
+func TestVanityCancel(t *testing.T) {
+	v, err := NewVanity(&MainNetParams, "1zzzzzzzzz", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	if _, err := v.Search(ctx, 4); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
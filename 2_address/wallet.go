@@ -0,0 +1,364 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/btcsuite/golangcrypto/scrypt"
+)
+
+const (
+	WalletVersion = 1 // Version of the wallet file format
+
+	pemType = "PRIVATE KEY" // PEM block type of PKCS#8 keys
+
+	saltSize = 32 // Size of the scrypt salt
+	keySize  = 32 // Size of the AES-256 key
+)
+
+// Default scrypt parameters. Deriving the key takes on the order of 100ms on
+// a modern machine which makes brute forcing the passphrase expensive.
+var (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Bounds of the scrypt parameters that are accepted from a wallet file. The
+// parameters determine how much memory and time deriving the key takes, so a
+// crafted wallet file could otherwise make the process allocate gigabytes.
+const (
+	maxScryptN      = 1 << 20   // Maximum CPU/memory cost
+	maxScryptR      = 32        // Maximum block size
+	maxScryptP      = 16        // Maximum parallelization
+	maxScryptMemory = 256 << 20 // Maximum memory use of 128*N*R bytes
+)
+
+// checkScryptParams returns an error if the scrypt parameters n, r and p are
+// invalid or exceed the bounds above.
+func checkScryptParams(n, r, p int) error {
+	if n < 2 || n > maxScryptN || n&(n-1) != 0 {
+		return fmt.Errorf("invalid scrypt N: %v", n)
+	}
+	if r < 1 || r > maxScryptR {
+		return fmt.Errorf("invalid scrypt r: %v", r)
+	}
+	if p < 1 || p > maxScryptP {
+		return fmt.Errorf("invalid scrypt p: %v", p)
+	}
+	if 128*n*r > maxScryptMemory {
+		return fmt.Errorf("scrypt parameters use %v bytes of memory, "+
+			"maximum is %v", 128*n*r, maxScryptMemory)
+	}
+	return nil
+}
+
+// walletKey is a single labeled key as it is stored in the wallet file. The
+// public key is stored in the clear so that addresses can be exported without
+// unlocking the wallet. The private key is PKCS#8 encoded and sealed with
+// AES-GCM; the public key is used as additional data in order to bind the two
+// together.
+type walletKey struct {
+	Label     string `json:"label"`     // Unique label
+	PublicKey []byte `json:"publickey"` // PKIX encoded public key
+	Encrypted []byte `json:"encrypted"` // nonce+AES-GCM(PKCS#8 private key)
+}
+
+// walletFile is the on disk representation of a Wallet.
+type walletFile struct {
+	Version int         `json:"version"` // Wallet file version
+	Network string      `json:"network"` // Network name
+	Salt    []byte      `json:"salt"`    // scrypt salt
+	N       int         `json:"n"`       // scrypt CPU/memory cost
+	R       int         `json:"r"`       // scrypt block size
+	P       int         `json:"p"`       // scrypt parallelization
+	Check   []byte      `json:"check"`   // Verifies the passphrase
+	Keys    []walletKey `json:"keys"`    // Encrypted keys
+}
+
+// WalletAddress is a labeled address that is exported from a Wallet.
+type WalletAddress struct {
+	Label   string   // Label of the key
+	Address *Address // Address of the key
+}
+
+// Wallet stores multiple labeled private keys that are encrypted with a key
+// that is derived from a passphrase. A locked wallet only exposes public
+// information.
+type Wallet struct {
+	net  *Params     // Network of all addresses in the wallet
+	file walletFile  // Serializable wallet
+	aead cipher.AEAD // Encryption cipher, nil when locked
+}
+
+// newAEAD derives the encryption key from passphrase and returns the AES-GCM
+// cipher.
+func (w *Wallet) newAEAD(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, w.file.Salt, w.file.N, w.file.R,
+		w.file.P, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prepends the random nonce.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// unseal decrypts a blob that was created by seal.
+func unseal(aead cipher.AEAD, blob, additional []byte) ([]byte, error) {
+	if len(blob) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted blob")
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, blob[:n], blob[n:], additional)
+}
+
+// NewWallet returns an empty and unlocked wallet for network net that is
+// encrypted with passphrase.
+func NewWallet(net *Params, passphrase []byte) (*Wallet, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	w := &Wallet{
+		net: net,
+		file: walletFile{
+			Version: WalletVersion,
+			Network: net.Name,
+			Salt:    salt,
+			N:       scryptN,
+			R:       scryptR,
+			P:       scryptP,
+		},
+	}
+	aead, err := w.newAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.file.Check, err = seal(aead, nil, salt)
+	if err != nil {
+		return nil, err
+	}
+	w.aead = aead
+	return w, nil
+}
+
+// Locked returns true if the private keys are not accessible.
+func (w *Wallet) Locked() bool {
+	return w.aead == nil
+}
+
+// Lock forgets the encryption key.
+func (w *Wallet) Lock() {
+	w.aead = nil
+}
+
+// Unlock derives the encryption key from passphrase and makes the private keys
+// accessible.
+func (w *Wallet) Unlock(passphrase []byte) error {
+	aead, err := w.newAEAD(passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := unseal(aead, w.file.Check, w.file.Salt); err != nil {
+		return fmt.Errorf("invalid passphrase")
+	}
+	w.aead = aead
+	return nil
+}
+
+// find returns the index of the key with label.
+func (w *Wallet) find(label string) (int, error) {
+	for i, k := range w.file.Keys {
+		if k.Label == label {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("key not found: %v", label)
+}
+
+// AddKey encrypts key and stores it under label. The wallet must be unlocked.
+func (w *Wallet) AddKey(label string, key *PrivateKey) error {
+	if w.Locked() {
+		return fmt.Errorf("wallet locked")
+	}
+	if _, err := w.find(label); err == nil {
+		return fmt.Errorf("duplicate label: %v", label)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PrivateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(&key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	encrypted, err := seal(w.aead, der, pub)
+	if err != nil {
+		return err
+	}
+	w.file.Keys = append(w.file.Keys, walletKey{
+		Label:     label,
+		PublicKey: pub,
+		Encrypted: encrypted,
+	})
+	return nil
+}
+
+// GenerateKey creates a new private key and stores it under label. The wallet
+// must be unlocked.
+func (w *Wallet) GenerateKey(label string) (*PrivateKey, error) {
+	key, err := NewKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.AddKey(label, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Key decrypts and returns the private key that is stored under label. The
+// wallet must be unlocked.
+func (w *Wallet) Key(label string) (*PrivateKey, error) {
+	if w.Locked() {
+		return nil, fmt.Errorf("wallet locked")
+	}
+	i, err := w.find(label)
+	if err != nil {
+		return nil, err
+	}
+	k := w.file.Keys[i]
+	der, err := unseal(w.aead, k.Encrypted, k.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt key: %v", label)
+	}
+	return parsePKCS8(der)
+}
+
+// Addresses returns the addresses of all keys in the wallet. This does not
+// require the wallet to be unlocked.
+func (w *Wallet) Addresses() ([]WalletAddress, error) {
+	addrs := make([]WalletAddress, 0, len(w.file.Keys))
+	for _, k := range w.file.Keys {
+		pub, err := x509.ParsePKIXPublicKey(k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		ecpub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid public key: %v", k.Label)
+		}
+		pk := PublicKey{*ecpub}
+		addrs = append(addrs, WalletAddress{
+			Label:   k.Label,
+			Address: pk.Address(w.net),
+		})
+	}
+	return addrs, nil
+}
+
+// ExportPEM returns the private key that is stored under label as an
+// unencrypted PEM encoded PKCS#8 blob. The wallet must be unlocked.
+func (w *Wallet) ExportPEM(label string) ([]byte, error) {
+	key, err := w.Key(label)
+	if err != nil {
+		return nil, err
+	}
+	return key.PEM()
+}
+
+// ImportPEM decodes a PEM encoded PKCS#8 private key and stores it under
+// label. The wallet must be unlocked.
+func (w *Wallet) ImportPEM(label string, blob []byte) error {
+	key, err := NewKeyFromPEM(blob)
+	if err != nil {
+		return err
+	}
+	return w.AddKey(label, key)
+}
+
+// Save writes the encrypted wallet to filename. Only the owner can read the
+// resulting file.
+func (w *Wallet) Save(filename string) error {
+	blob, err := json.MarshalIndent(w.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, blob, 0600)
+}
+
+// OpenWallet reads a wallet from filename. The returned wallet is locked.
+func OpenWallet(filename string) (*Wallet, error) {
+	blob, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var wf walletFile
+	if err := json.Unmarshal(blob, &wf); err != nil {
+		return nil, err
+	}
+	if wf.Version != WalletVersion {
+		return nil, fmt.Errorf("unsupported wallet version: %v",
+			wf.Version)
+	}
+	net, err := paramsForName(wf.Network)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScryptParams(wf.N, wf.R, wf.P); err != nil {
+		return nil, err
+	}
+	return &Wallet{net: net, file: wf}, nil
+}
+
+// parsePKCS8 decodes a DER encoded PKCS#8 private key.
+func parsePKCS8(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	eckey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || eckey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("not a P-256 ECDSA key")
+	}
+	return &PrivateKey{*eckey}, nil
+}
+
+// PEM returns the private key as an unencrypted PEM encoded PKCS#8 blob.
+func (p PrivateKey) PEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(&p.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+// NewKeyFromPEM decodes a PEM encoded PKCS#8 private key.
+func NewKeyFromPEM(blob []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(blob)
+	if block == nil || block.Type != pemType {
+		return nil, fmt.Errorf("invalid PEM block")
+	}
+	return parsePKCS8(block.Bytes)
+}
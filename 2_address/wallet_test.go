@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"path/filepath"
+	"testing"
+)
+
+func TestWallet(t *testing.T) {
+	passphrase := []byte("Decred is money!")
+	w, err := NewWallet(&TestNetParams, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := w.GenerateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.GenerateKey("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.GenerateKey("alice"); err == nil {
+		t.Fatalf("duplicate label accepted")
+	}
+
+	filename := filepath.Join(t.TempDir(), "wallet.json")
+	if err := w.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	ww, err := OpenWallet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ww.Locked() {
+		t.Fatalf("wallet not locked")
+	}
+
+	// Export addresses without unlocking.
+	addrs, err := ww.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 {
+		t.Fatalf("invalid number of addresses: %v", len(addrs))
+	}
+	for _, a := range addrs {
+		t.Logf("%-5v: %v", a.Label, a.Address)
+		if !a.Address.IsForNet(&TestNetParams) {
+			t.Fatalf("invalid network: %v", a.Address.Net)
+		}
+	}
+	pk := NewPublicKey(alice.Public())
+	if addrs[0].Address.String() != pk.Address(&TestNetParams).String() {
+		t.Fatalf("address mismatch")
+	}
+
+	// Private keys require the wallet to be unlocked.
+	if _, err := ww.Key("alice"); err == nil {
+		t.Fatalf("key returned from locked wallet")
+	}
+	if err := ww.Unlock([]byte("Decred is not money!")); err == nil {
+		t.Fatalf("unlocked with invalid passphrase")
+	}
+	if err := ww.Unlock(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ww.Key("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.D.Cmp(alice.D) != 0 {
+		t.Fatalf("private key mismatch")
+	}
+	hash := sha256.Sum256([]byte("Hello world!"))
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pk.Verify(hash[:], signature) {
+		t.Fatalf("verify failed")
+	}
+
+	ww.Lock()
+	if _, err := ww.GenerateKey("charlie"); err == nil {
+		t.Fatalf("key added to locked wallet")
+	}
+}
+
+func TestWalletScryptBounds(t *testing.T) {
+	w, err := NewWallet(&TestNetParams, []byte("Decred is money!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "wallet.json")
+
+	tests := []struct {
+		name    string
+		n, r, p int
+		valid   bool
+	}{
+		{"default", scryptN, scryptR, scryptP, true},
+		{"maximum", maxScryptN, 2, maxScryptP, true},
+		{"huge n", 1 << 30, scryptR, scryptP, false},
+		{"odd n", scryptN + 1, scryptR, scryptP, false},
+		{"zero r", scryptN, 0, scryptP, false},
+		{"huge r", scryptN, 1 << 20, scryptP, false},
+		{"huge p", scryptN, scryptR, 1 << 20, false},
+		{"memory", maxScryptN, maxScryptR, scryptP, false},
+	}
+	for _, test := range tests {
+		w.file.N, w.file.R, w.file.P = test.n, test.r, test.p
+		if err := w.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+		_, err := OpenWallet(filename)
+		if test.valid != (err == nil) {
+			t.Fatalf("%v: unexpected result: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+}
+
+func TestWalletPEM(t *testing.T) {
+	passphrase := []byte("Decred is money!")
+	w, err := NewWallet(&MainNetParams, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := w.GenerateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := w.ExportPEM("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", blob)
+
+	ww, err := NewWallet(&MainNetParams, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ww.ImportPEM("imported", blob); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ww.Key("imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.D.Cmp(key.D) != 0 {
+		t.Fatalf("private key mismatch")
+	}
+
+	if _, err := NewKeyFromPEM([]byte("not a pem")); err == nil {
+		t.Fatalf("invalid PEM accepted")
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var Empty [sha256.Size]byte // All zero sha256 value
+
+const (
+	// medianTimeBlocks is the number of previous blocks that are used to
+	// calculate the median time past.
+	medianTimeBlocks = 11
+
+	// maxFutureDrift is how far a block timestamp may be ahead of the
+	// clock.
+	maxFutureDrift = 2 * time.Hour
+)
+
+// encodeUint64 encodes a uint64 to big endian notation. This code uses big
+// endian in order to make the resulting values more readable for humans.
+func encodeUint64(x uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, x)
+	return b
+}
+
+// Block represents a single block in the blockchain. It is linked to the prior
+// block via the PreviousBlockHash.
+type Block struct {
+	Timestamp         int64  // Timestamp block was mined
+	Data              []byte // Blockchain data
+	PreviousBlockHash []byte // Previous block hash in order link blocks
+	Hash              []byte // PoW hash of this block
+	Nonce             uint64 // Nonce used to calculate Hash
+}
+
+// NewBlock returns a block that is linked to previousBlockHash and stamped
+// with the current time of clock.
+func NewBlock(clock Clock, data, previousBlockHash []byte) Block {
+	timestamp := clock.Now().Unix()
+	return Block{
+		Timestamp:         timestamp,
+		Data:              data,
+		PreviousBlockHash: previousBlockHash,
+	}
+}
+
+// serialize returns timestamp, data, previous block hash and nonce. This is
+// what the block hash and the proof-of-work hash are calculated over.
+func (b Block) serialize() []byte {
+	t := encodeUint64(uint64(b.Timestamp))
+	n := encodeUint64(b.Nonce)
+	return bytes.Join([][]byte{t, b.Data, b.PreviousBlockHash, n},
+		[]byte{})
+}
+
+// Size returns the size of the serialized block. It must not exceed the
+// MaxBlockSize of the network.
+func (b Block) Size() int {
+	return len(b.serialize())
+}
+
+// calculateHash returns the hash that identifies the block.
+func (b Block) calculateHash() []byte {
+	hash := sha256.Sum256(b.serialize())
+	return hash[:]
+}
+
+// Verify ensures that the block is valid by hashing timestamp, data and nonce.
+func (b Block) Verify() bool {
+	return bytes.Equal(b.calculateHash(), b.Hash)
+}
+
+// PowHash returns the proof-of-work hash of the block.
+func (b Block) PowHash(hasher PowHasher) []byte {
+	return hasher.PowHash(b.serialize())
+}
+
+// powTarget returns the value that a block hash must be below in order to
+// satisfy difficulty.
+func powTarget(difficulty uint) *big.Int {
+	target := big.NewInt(1)
+	return target.Lsh(target, uint(256-difficulty))
+}
+
+// CheckProofOfWork returns true if the proof-of-work hash of the block
+// satisfies difficulty. It does not verify the block hash.
+func (b Block) CheckProofOfWork(hasher PowHasher, difficulty uint) bool {
+	pow := new(big.Int).SetBytes(b.PowHash(hasher))
+	return pow.Cmp(powTarget(difficulty)) == -1
+}
+
+// Mine attempts to mine the block within the provided range. The nonce is
+// incremented until the proof-of-work hash calculated by hasher satisfies
+// difficulty, after which the block hash is set.
+func (b *Block) Mine(hasher PowHasher, difficulty uint) error {
+	target := powTarget(difficulty)
+	blob := b.serialize()
+	n := blob[len(blob)-8:] // Nonce is serialized last
+	bi := big.Int{}
+	for i := uint64(0); i < math.MaxInt64; i++ {
+		binary.BigEndian.PutUint64(n, i)
+		bi.SetBytes(hasher.PowHash(blob))
+		if bi.Cmp(target) == -1 {
+			b.Nonce = i
+			b.Hash = b.calculateHash()
+			return nil
+		}
+	}
+	return fmt.Errorf("no solution for block")
+}
+
+// Blockchain is the blockchain context that houses an array of blocks.
+type Blockchain struct {
+	params *Params   // Network parameters
+	hasher PowHasher // Proof-of-work algorithm
+	clock  Clock     // Source of the current time
+	blocks []*Block
+	index  map[string]int // Block hash to height
+}
+
+// Append adds a block, if valid, to the end of the blockchain. The first
+// violated consensus rule is returned as a *BlockError.
+func (b *Blockchain) Append(blk *Block) error {
+	if errs := b.checkBlock(len(b.blocks), blk); len(errs) != 0 {
+		return errs[0]
+	}
+	if b.index == nil {
+		b.index = make(map[string]int)
+	}
+	b.index[string(blk.Hash)] = len(b.blocks)
+	b.blocks = append(b.blocks, blk)
+	return nil
+}
+
+// removeTip removes the last block from the blockchain. It is used to undo an
+// Append that could not be persisted. The genesis block is never removed.
+func (b *Blockchain) removeTip() {
+	if len(b.blocks) <= 1 {
+		return
+	}
+	tip := b.blocks[len(b.blocks)-1]
+	delete(b.index, string(tip.Hash))
+	b.blocks = b.blocks[:len(b.blocks)-1]
+}
+
+// Mine mines blk with the proof-of-work algorithm and difficulty of the
+// blockchain. Blocks that exceed the maximum block size are refused before any
+// work is wasted on them.
+func (b Blockchain) Mine(blk *Block) error {
+	if size := blk.Size(); size > b.params.MaxBlockSize {
+		return &BlockError{
+			Height: len(b.blocks),
+			Rule:   RuleBlockSize,
+			Reason: fmt.Sprintf("%v bytes, maximum is %v", size,
+				b.params.MaxBlockSize),
+		}
+	}
+	return blk.Mine(b.hasher, b.params.Difficulty)
+}
+
+// PrepareBlock returns a block template based on the current height of the
+// blockchain. The timestamp is raised to the median time past if the clock is
+// behind it so that the block is acceptable.
+func (b *Blockchain) PrepareBlock(data []byte) *Block {
+	var previousBlockHash []byte
+	if len(b.blocks) == 0 {
+		// Genesis
+		previousBlockHash = Empty[:]
+	} else {
+		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
+	}
+	blk := NewBlock(b.clock, data, previousBlockHash)
+	if mtp := b.medianTimePast(len(b.blocks)); blk.Timestamp < mtp {
+		blk.Timestamp = mtp
+	}
+	return &blk
+}
+
+// Block returns a copy of the block at the specified block height.
+func (b Blockchain) Block(block int) (Block, error) {
+	if block < 0 || block >= len(b.blocks) {
+		return Block{}, fmt.Errorf("invalid block: %v", block)
+	}
+	return *b.blocks[block], nil
+}
+
+// BlockByHash returns a copy of the block with the specified hash along with
+// its height.
+func (b Blockchain) BlockByHash(hash []byte) (Block, int, error) {
+	height, ok := b.index[string(hash)]
+	if !ok {
+		return Block{}, 0, fmt.Errorf("block not found: %x", hash)
+	}
+	return *b.blocks[height], height, nil
+}
+
+// HasBlock returns true if the block with the specified hash is part of the
+// blockchain.
+func (b Blockchain) HasBlock(hash []byte) bool {
+	_, ok := b.index[string(hash)]
+	return ok
+}
+
+// Tip returns a copy of the last block along with its height.
+func (b Blockchain) Tip() (Block, int, error) {
+	if len(b.blocks) == 0 {
+		return Block{}, 0, fmt.Errorf("empty blockchain")
+	}
+	height := len(b.blocks) - 1
+	return *b.blocks[height], height, nil
+}
+
+// Len returns the current blockchain height.
+func (b Blockchain) Len() int {
+	return len(b.blocks)
+}
+
+// medianTimePast returns the median timestamp of the up to medianTimeBlocks
+// blocks that precede height. A block at height must not have a timestamp
+// before it. Since the median moves slowly, a single miner with a wrong clock
+// can't push it around. Missing blocks, which Validate may come across, are
+// skipped.
+func (b Blockchain) medianTimePast(height int) int64 {
+	start := height - medianTimeBlocks
+	if start < 0 {
+		start = 0
+	}
+	if height <= start {
+		return 0
+	}
+	timestamps := make([]int64, 0, height-start)
+	for _, blk := range b.blocks[start:height] {
+		if blk != nil {
+			timestamps = append(timestamps, blk.Timestamp)
+		}
+	}
+	if len(timestamps) == 0 {
+		return 0
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2]
+}
+
+// MedianTimePast returns the median time past of the next block.
+func (b Blockchain) MedianTimePast() time.Time {
+	return time.Unix(b.medianTimePast(len(b.blocks)), 0)
+}
+
+// Params returns the network parameters of the blockchain.
+func (b Blockchain) Params() *Params {
+	return b.params
+}
+
+// PowHasher returns the proof-of-work algorithm of the blockchain.
+func (b Blockchain) PowHasher() PowHasher {
+	return b.hasher
+}
+
+// NewBlockChain returns a blockchain context that has a genesis block. The
+// genesis data and the difficulty are taken from the network parameters and
+// blocks are mined with hasher. All timestamps are taken from clock.
+func NewBlockChain(params *Params, hasher PowHasher,
+	clock Clock) (*Blockchain, error) {
+
+	b := &Blockchain{params: params, hasher: hasher, clock: clock}
+	blk := b.PrepareBlock(params.GenesisData)
+	err := blk.Mine(hasher, params.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+	err = b.Append(blk)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
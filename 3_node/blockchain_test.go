@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (b Block) dump(t *testing.T) {
+	t.Logf("Timestamp        : %v\n", time.Unix(b.Timestamp, 0))
+	t.Logf("PreviousBlockHash: %x\n", b.PreviousBlockHash)
+	t.Logf("Hash             : %x\n", b.Hash)
+	t.Logf("Data             : %s\n", string(b.Data))
+	t.Logf("Nonce            : %v\n", b.Nonce)
+}
+
+func (b *Blockchain) corrupt(block int, data []byte) error {
+	if block < 0 || block >= len(b.blocks) {
+		return fmt.Errorf("invalid block: %v", block)
+	}
+	b.blocks[block].Data = data
+	return nil
+}
+
+func TestBlockChain(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blk := b.PrepareBlock([]byte("Send 1 Decred to Alice"))
+	err = b.Mine(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Append(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blk = b.PrepareBlock([]byte("Send 2 Decred to Bob"))
+	err = b.Mine(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Append(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < b.Len(); i++ {
+		t.Log(strings.Repeat("=", 80))
+		blk, err := b.Block(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk.dump(t)
+		if !blk.Verify() {
+			t.Fatalf("corrupt")
+		}
+	}
+
+	// Corrupt data and try again
+	if err := b.corrupt(1, []byte("Send 2 Decred to Alice")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < b.Len(); i++ {
+		t.Log(strings.Repeat("=", 80))
+		blk, err := b.Block(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk.dump(t)
+		if !blk.Verify() && i == 1 {
+			t.Logf("Block 1 corrupt")
+			return
+		}
+	}
+}
+
+func TestBlockLookup(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, nil, 3)
+
+	for _, height := range []int{-1, b.Len(), b.Len() + 1} {
+		if _, err := b.Block(height); err == nil {
+			t.Fatalf("block %v: expected error", height)
+		}
+	}
+
+	for i := 0; i < b.Len(); i++ {
+		blk, err := b.Block(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !b.HasBlock(blk.Hash) {
+			t.Fatalf("block %v: not indexed", i)
+		}
+		found, height, err := b.BlockByHash(blk.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if height != i || !bytes.Equal(found.Hash, blk.Hash) {
+			t.Fatalf("block %v: got height %v hash %x", i, height,
+				found.Hash)
+		}
+	}
+	if b.HasBlock(Empty[:]) {
+		t.Fatalf("unexpected block")
+	}
+	if _, _, err := b.BlockByHash(Empty[:]); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	tip, height, err := b.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := b.Block(b.Len() - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != b.Len()-1 || !bytes.Equal(tip.Hash, last.Hash) {
+		t.Fatalf("invalid tip at height %v", height)
+	}
+	if _, _, err := (&Blockchain{}).Tip(); err == nil {
+		t.Fatalf("expected error on empty blockchain")
+	}
+}
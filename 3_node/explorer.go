@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The explorer is a small web UI that renders the blockchain. Every block
+// links to its parent via the PreviousBlockHash so that the chain can be
+// followed back to genesis by clicking.
+
+const explorerPageSize = 20 // Number of blocks on the index page
+
+// explorerTemplates contains all explorer pages. The layout is shared and each
+// page defines its own content.
+var explorerTemplates = template.Must(template.New("layout").Funcs(
+	template.FuncMap{
+		"hex": hex.EncodeToString,
+		"time": func(t int64) string {
+			return time.Unix(t, 0).UTC().Format(time.RFC3339)
+		},
+	}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>educoin {{.Net}} explorer</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.hash { font-family: monospace; }
+.valid { color: green; }
+.invalid { color: red; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1><a href="/">educoin {{.Net}} explorer</a></h1>
+<form action="/search">
+<input name="q" size="70" placeholder="height, block hash or address">
+<input type="submit" value="Search">
+</form>
+{{template "content" .}}
+</body>
+</html>
+{{define "verify"}}{{if .Valid}}<span class="valid">&#10004; valid</span>{{else}}<span class="invalid">&#10008; invalid</span>{{end}}{{end}}
+{{define "prev"}}{{if .Genesis}}<span class="hash">{{hex .PreviousBlockHash}}</span>{{else}}<a class="hash" href="/block/{{hex .PreviousBlockHash}}">{{hex .PreviousBlockHash}}</a>{{end}}{{end}}
+`))
+
+var explorerIndex = template.Must(template.Must(explorerTemplates.Clone()).
+	Parse(`{{define "content"}}
+<h2>Recent blocks</h2>
+<table>
+<tr><th>Height</th><th>Timestamp</th><th>Hash</th><th>Previous hash</th><th>Nonce</th><th>Data</th><th>Verify</th></tr>
+{{range .Blocks}}
+<tr>
+<td>{{.Height}}</td>
+<td>{{time .Timestamp}}</td>
+<td><a class="hash" href="/block/{{hex .Hash}}">{{hex .Hash}}</a></td>
+<td>{{template "prev" .}}</td>
+<td>{{.Nonce}}</td>
+<td>{{printf "%s" .Data}}</td>
+<td>{{template "verify" .}}</td>
+</tr>
+{{end}}
+</table>
+{{if ge .Older 0}}<p><a href="/?top={{.Older}}">Older blocks</a></p>{{end}}
+{{end}}`))
+
+var explorerBlock = template.Must(template.Must(explorerTemplates.Clone()).
+	Parse(`{{define "content"}}
+{{with .Block}}
+<h2>Block {{.Height}}</h2>
+<table>
+<tr><th>Height</th><td>{{.Height}}</td></tr>
+<tr><th>Timestamp</th><td>{{time .Timestamp}}</td></tr>
+<tr><th>Hash</th><td class="hash">{{hex .Hash}}</td></tr>
+<tr><th>Previous hash</th><td>{{template "prev" .}}</td></tr>
+<tr><th>Nonce</th><td>{{.Nonce}}</td></tr>
+<tr><th>Data</th><td>{{printf "%s" .Data}}</td></tr>
+<tr><th>Data (hex)</th><td class="hash">{{hex .Data}}</td></tr>
+<tr><th>Verify</th><td>{{template "verify" .}}</td></tr>
+</table>
+{{end}}
+{{end}}`))
+
+var explorerAddress = template.Must(template.Must(explorerTemplates.Clone()).
+	Parse(`{{define "content"}}
+{{with .Address}}
+<h2>Address {{.}}</h2>
+<table>
+<tr><th>Network</th><td>{{.Net}}</td></tr>
+<tr><th>Format</th><td>{{.Format}}</td></tr>
+<tr><th>Version</th><td>{{.Version}}</td></tr>
+<tr><th>PubKeyHash</th><td class="hash">{{hex .PubKeyHash}}</td></tr>
+</table>
+{{end}}
+<h2>Blocks that mention this address</h2>
+{{if .Blocks}}
+<table>
+<tr><th>Height</th><th>Hash</th><th>Data</th></tr>
+{{range .Blocks}}
+<tr>
+<td>{{.Height}}</td>
+<td><a class="hash" href="/block/{{hex .Hash}}">{{hex .Hash}}</a></td>
+<td>{{printf "%s" .Data}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>None. Blocks do not carry transactions yet; only block data that contains
+the address is listed.</p>
+{{end}}
+{{end}}`))
+
+// explorerBlockInfo is a block as it is rendered by the explorer.
+type explorerBlockInfo struct {
+	Block
+	Height  int  // Block height
+	Valid   bool // Result of Block.Verify
+	Genesis bool // True for the genesis block
+}
+
+// Explorer is the context of the block explorer. It implements http.Handler.
+type Explorer struct {
+	lock       sync.Locker // lock to synchronize blockchain access
+	blockchain *Blockchain
+	mux        *http.ServeMux
+}
+
+// NewExplorer returns a block explorer for blockchain. The blockchain is only
+// accessed while holding lock.
+func NewExplorer(blockchain *Blockchain, lock sync.Locker) *Explorer {
+	e := &Explorer{
+		lock:       lock,
+		blockchain: blockchain,
+		mux:        http.NewServeMux(),
+	}
+	e.mux.HandleFunc("/", e.handleIndex)
+	e.mux.HandleFunc("/block/", e.handleBlock)
+	e.mux.HandleFunc("/address/", e.handleAddress)
+	e.mux.HandleFunc("/search", e.handleSearch)
+	return e
+}
+
+// ServeHTTP dispatches explorer requests.
+func (e *Explorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// info returns the explorer representation of the block at height. The lock
+// must be held.
+func (e *Explorer) info(height int) (*explorerBlockInfo, error) {
+	blk, err := e.blockchain.Block(height)
+	if err != nil {
+		return nil, err
+	}
+	return &explorerBlockInfo{
+		Block:   blk,
+		Height:  height,
+		Valid:   blk.Verify(),
+		Genesis: height == 0,
+	}, nil
+}
+
+// render executes page and reports template errors.
+func (e *Explorer) render(w http.ResponseWriter, page *template.Template,
+	data map[string]interface{}) {
+
+	data["Net"] = e.blockchain.Params()
+	var b bytes.Buffer
+	if err := page.Execute(&b, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// handleIndex renders the most recent blocks, newest first. The top query
+// parameter selects the first height that is rendered.
+func (e *Explorer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	top := e.blockchain.Len() - 1
+	if t := r.URL.Query().Get("top"); t != "" {
+		height, err := strconv.Atoi(t)
+		if err != nil || height < 0 || height > top {
+			http.Error(w, "invalid height", http.StatusBadRequest)
+			return
+		}
+		top = height
+	}
+
+	var blocks []*explorerBlockInfo
+	for i := top; i >= 0 && i > top-explorerPageSize; i-- {
+		info, err := e.info(i)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		blocks = append(blocks, info)
+	}
+	older := top - explorerPageSize // Negative if genesis is on this page
+	if older < 0 {
+		older = -1
+	}
+	e.render(w, explorerIndex, map[string]interface{}{
+		"Blocks": blocks,
+		"Older":  older,
+	})
+}
+
+// handleBlock renders the block with the hash or height in the path.
+func (e *Explorer) handleBlock(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/block/")
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	height := -1
+	if hash, err := hex.DecodeString(id); err == nil && len(hash) > 4 {
+		if _, h, err := e.blockchain.BlockByHash(hash); err == nil {
+			height = h
+		}
+	} else if h, err := strconv.Atoi(id); err == nil {
+		height = h
+	}
+	info, err := e.info(height)
+	if err != nil {
+		http.Error(w, "block not found", http.StatusNotFound)
+		return
+	}
+	e.render(w, explorerBlock, map[string]interface{}{
+		"Block": info,
+	})
+}
+
+// handleAddress renders the address in the path along with all blocks whose
+// data mentions it.
+func (e *Explorer) handleAddress(w http.ResponseWriter, r *http.Request) {
+	a, err := DecodeAddress(strings.TrimPrefix(r.URL.Path, "/address/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Match every encoding of the address.
+	forms := [][]byte{[]byte(a.String())}
+	for _, f := range []AddressFormat{FormatBech32, FormatBech32m} {
+		if s, err := a.Encode(f); err == nil {
+			forms = append(forms, []byte(s))
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var blocks []*explorerBlockInfo
+	for i := 0; i < e.blockchain.Len(); i++ {
+		info, err := e.info(i)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, form := range forms {
+			if bytes.Contains(info.Data, form) {
+				blocks = append(blocks, info)
+				break
+			}
+		}
+	}
+	e.render(w, explorerAddress, map[string]interface{}{
+		"Address": a,
+		"Blocks":  blocks,
+	})
+}
+
+// handleSearch redirects to the block or address page that matches the query.
+func (e *Explorer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if _, err := DecodeAddress(q); err == nil {
+		http.Redirect(w, r, "/address/"+url.PathEscape(q),
+			http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/block/"+url.PathEscape(q), http.StatusFound)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// explorerGet fetches path from the explorer at url and returns the status
+// code and body.
+func explorerGet(t *testing.T, url, path string) (int, string) {
+	r, err := http.Get(url + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.StatusCode, string(body)
+}
+
+func TestExplorer(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewPublicKey(key.Public()).Address(&SimNetParams)
+	blk := b.PrepareBlock([]byte("Send 1 Decred to " + a.String()))
+	if err := b.Mine(blk); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Append(blk); err != nil {
+		t.Fatal(err)
+	}
+	genesis, err := b.Block(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(NewExplorer(b, &sync.Mutex{}))
+	defer s.Close()
+
+	// The index lists both blocks and links the block to genesis.
+	code, body := explorerGet(t, s.URL, "/")
+	if code != http.StatusOK {
+		t.Fatalf("index: %v", code)
+	}
+	for _, want := range []string{
+		`href="/block/` + hex.EncodeToString(blk.Hash) + `"`,
+		`href="/block/` + hex.EncodeToString(genesis.Hash) + `"`,
+		"Send 1 Decred to",
+		`class="valid"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("index does not contain %q", want)
+		}
+	}
+	if strings.Contains(body, `class="invalid"`) {
+		t.Fatalf("index contains invalid block")
+	}
+
+	// Follow the link to the previous block.
+	code, body = explorerGet(t, s.URL,
+		"/block/"+hex.EncodeToString(blk.PreviousBlockHash))
+	if code != http.StatusOK {
+		t.Fatalf("block: %v", code)
+	}
+	if !strings.Contains(body, "Block 0") {
+		t.Fatalf("previous block is not genesis")
+	}
+
+	// Blocks can also be retrieved by height.
+	code, body = explorerGet(t, s.URL, "/block/1")
+	if code != http.StatusOK || !strings.Contains(body, "Block 1") {
+		t.Fatalf("block by height: %v", code)
+	}
+
+	code, _ = explorerGet(t, s.URL, "/block/"+strings.Repeat("00", 32))
+	if code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", code)
+	}
+
+	// The address page lists the block that mentions the address.
+	code, body = explorerGet(t, s.URL, "/address/"+a.String())
+	if code != http.StatusOK {
+		t.Fatalf("address: %v", code)
+	}
+	if !strings.Contains(body, hex.EncodeToString(blk.Hash)) {
+		t.Fatalf("address page does not list block")
+	}
+
+	code, _ = explorerGet(t, s.URL, "/address/nope")
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %v", code)
+	}
+
+	// Corrupt a block and make sure it is flagged.
+	blk.Nonce++
+	_, body = explorerGet(t, s.URL, "/")
+	if !strings.Contains(body, `class="invalid"`) {
+		t.Fatalf("corrupt block not flagged")
+	}
+}
+
+func TestExplorerPages(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, nil, explorerPageSize)
+
+	s := httptest.NewServer(NewExplorer(b, &sync.Mutex{}))
+	defer s.Close()
+
+	// Genesis is the only block on the second page.
+	_, body := explorerGet(t, s.URL, "/")
+	if !strings.Contains(body, `href="/?top=0"`) {
+		t.Fatalf("no link to genesis")
+	}
+	genesis, err := b.Block(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body = explorerGet(t, s.URL, "/?top=0")
+	if !strings.Contains(body, hex.EncodeToString(genesis.Hash)) ||
+		strings.Contains(body, "Older blocks") {
+		t.Fatalf("invalid last page")
+	}
+
+	// The query is a single path element.
+	code, body := explorerGet(t, s.URL, "/search?q=1")
+	if code != http.StatusOK || !strings.Contains(body, "Block 1") {
+		t.Fatalf("search by height: %v", code)
+	}
+	code, _ = explorerGet(t, s.URL, "/search?q="+url.QueryEscape("1?x"))
+	if code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", code)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A chain snapshot is a portable binary container that can be shared between
+// nodes so that a chain does not have to be mined again. The layout is:
+//
+//	magic      "EDUCHAIN"
+//	version    uint16
+//	network    varbytes
+//	pow        varbytes, proof-of-work algorithm, since version 2
+//	height     uvarint, number of blocks
+//	tip hash   varbytes
+//	blocks     height times: timestamp int64, nonce uint64, data varbytes,
+//	           previous block hash varbytes, hash varbytes
+//	checksum   sha256 of everything above
+//
+// Fixed size integers are big endian. Varbytes is the uvarint length of the
+// value followed by the value. Version 1 snapshots were written before the
+// proof-of-work algorithm could be chosen and are imported with sha256.
+
+const (
+	snapshotMagic   = "EDUCHAIN" // Identifies a chain snapshot
+	snapshotVersion = 2          // Current snapshot version
+
+	maxSnapshotField = 1 << 20 // Maximum length of a single varbytes field
+)
+
+var (
+	// ErrSnapshotChecksum is returned when a snapshot is corrupt.
+	ErrSnapshotChecksum = errors.New("invalid snapshot checksum")
+)
+
+// snapshotWriter encodes snapshot fields into a buffer.
+type snapshotWriter struct {
+	bytes.Buffer
+}
+
+// writeUint64 writes x in big endian notation.
+func (w *snapshotWriter) writeUint64(x uint64) {
+	w.Write(encodeUint64(x))
+}
+
+// writeUvarint writes x as a uvarint.
+func (w *snapshotWriter) writeUvarint(x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	w.Write(b[:n])
+}
+
+// writeVarBytes writes the uvarint length of b followed by b.
+func (w *snapshotWriter) writeVarBytes(b []byte) {
+	w.writeUvarint(uint64(len(b)))
+	w.Write(b)
+}
+
+// snapshotReader decodes snapshot fields from a buffer.
+type snapshotReader struct {
+	*bytes.Reader
+}
+
+// readUint64 reads a big endian uint64.
+func (r snapshotReader) readUint64() (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// readVarBytes reads a uvarint length followed by that many bytes.
+func (r snapshotReader) readVarBytes() ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > maxSnapshotField {
+		return nil, fmt.Errorf("field too long: %v", l)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Export writes the blockchain to w as a chain snapshot.
+func (b Blockchain) Export(w io.Writer) error {
+	tip, height, err := b.Tip()
+	if err != nil {
+		return err
+	}
+
+	var sw snapshotWriter
+	sw.WriteString(snapshotMagic)
+	sw.Write([]byte{0, snapshotVersion})
+	sw.writeVarBytes([]byte(b.params.Name))
+	sw.writeVarBytes([]byte(b.hasher.Name()))
+	sw.writeUvarint(uint64(height + 1))
+	sw.writeVarBytes(tip.Hash)
+	for _, blk := range b.blocks {
+		sw.writeUint64(uint64(blk.Timestamp))
+		sw.writeUint64(blk.Nonce)
+		sw.writeVarBytes(blk.Data)
+		sw.writeVarBytes(blk.PreviousBlockHash)
+		sw.writeVarBytes(blk.Hash)
+	}
+	checksum := sha256.Sum256(sw.Bytes())
+	sw.Write(checksum[:])
+
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// readBlock reads a single block from a snapshot.
+func (r snapshotReader) readBlock() (*Block, error) {
+	timestamp, err := r.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := r.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.readVarBytes()
+	if err != nil {
+		return nil, err
+	}
+	previousBlockHash, err := r.readVarBytes()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := r.readVarBytes()
+	if err != nil {
+		return nil, err
+	}
+	return &Block{
+		Timestamp:         int64(timestamp),
+		Data:              data,
+		PreviousBlockHash: previousBlockHash,
+		Hash:              hash,
+		Nonce:             nonce,
+	}, nil
+}
+
+// ImportBlockChain reads a chain snapshot from r. The snapshot checksum is
+// verified first and then every block is appended one by one so that it is
+// fully validated, including its hash and link to the previous block. Clock
+// is used to validate and create timestamps.
+func ImportBlockChain(r io.Reader, clock Clock) (*Blockchain, error) {
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) < len(snapshotMagic)+2+sha256.Size {
+		return nil, fmt.Errorf("snapshot too short")
+	}
+	if string(blob[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("not a chain snapshot")
+	}
+	payload := blob[:len(blob)-sha256.Size]
+	checksum := sha256.Sum256(payload)
+	if !bytes.Equal(checksum[:], blob[len(payload):]) {
+		return nil, ErrSnapshotChecksum
+	}
+
+	sr := snapshotReader{bytes.NewReader(payload[len(snapshotMagic):])}
+	var version [2]byte
+	if _, err := io.ReadFull(sr, version[:]); err != nil {
+		return nil, err
+	}
+	v := binary.BigEndian.Uint16(version[:])
+	if v < 1 || v > snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %v", v)
+	}
+	network, err := sr.readVarBytes()
+	if err != nil {
+		return nil, fmt.Errorf("network: %v", err)
+	}
+	params, err := paramsForName(string(network))
+	if err != nil {
+		return nil, err
+	}
+	var pow []byte // Empty selects sha256
+	if v >= 2 {
+		pow, err = sr.readVarBytes()
+		if err != nil {
+			return nil, fmt.Errorf("pow: %v", err)
+		}
+	}
+	hasher, err := powHasherForName(string(pow))
+	if err != nil {
+		return nil, err
+	}
+	height, err := binary.ReadUvarint(sr)
+	if err != nil {
+		return nil, fmt.Errorf("height: %v", err)
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("no genesis block")
+	}
+	tipHash, err := sr.readVarBytes()
+	if err != nil {
+		return nil, fmt.Errorf("tip hash: %v", err)
+	}
+
+	b := &Blockchain{params: params, hasher: hasher, clock: clock}
+	for i := uint64(0); i < height; i++ {
+		blk, err := sr.readBlock()
+		if err != nil {
+			return nil, fmt.Errorf("block %v: %v", i, err)
+		}
+		if err := b.Append(blk); err != nil {
+			return nil, err
+		}
+	}
+	if sr.Len() != 0 {
+		return nil, fmt.Errorf("%v trailing bytes", sr.Len())
+	}
+	if tip, _, _ := b.Tip(); !bytes.Equal(tip.Hash, tipHash) {
+		return nil, fmt.Errorf("tip mismatch: got %x, want %x", tip.Hash,
+			tipHash)
+	}
+	return b, nil
+}
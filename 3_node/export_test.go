@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+)
+
+// exportV1 returns b as a version 1 snapshot, which has no proof-of-work
+// algorithm.
+func exportV1(b *Blockchain) []byte {
+	tip, height, _ := b.Tip()
+	var sw snapshotWriter
+	sw.WriteString(snapshotMagic)
+	sw.Write([]byte{0, 1})
+	sw.writeVarBytes([]byte(b.params.Name))
+	sw.writeUvarint(uint64(height + 1))
+	sw.writeVarBytes(tip.Hash)
+	for _, blk := range b.blocks {
+		sw.writeUint64(uint64(blk.Timestamp))
+		sw.writeUint64(blk.Nonce)
+		sw.writeVarBytes(blk.Data)
+		sw.writeVarBytes(blk.PreviousBlockHash)
+		sw.writeVarBytes(blk.Hash)
+	}
+	checksum := sha256.Sum256(sw.Bytes())
+	sw.Write(checksum[:])
+	return sw.Bytes()
+}
+
+func TestExportImport(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, clock, 5)
+
+	var snapshot bytes.Buffer
+	if err := b.Export(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("snapshot: %v bytes", snapshot.Len())
+
+	imported, err := ImportBlockChain(bytes.NewReader(snapshot.Bytes()),
+		clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Params() != b.Params() || imported.Len() != b.Len() {
+		t.Fatalf("imported %v blocks on %v", imported.Len(),
+			imported.Params())
+	}
+	for i := 0; i < b.Len(); i++ {
+		want, _ := b.Block(i)
+		got, err := imported.Block(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Hash, want.Hash) ||
+			!bytes.Equal(got.Data, want.Data) ||
+			got.Timestamp != want.Timestamp || got.Nonce != want.Nonce {
+			t.Fatalf("block %v differs", i)
+		}
+	}
+
+	// Version 1 snapshots are still imported with sha256.
+	imported, err = ImportBlockChain(bytes.NewReader(exportV1(b)), clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.PowHasher() != SHA256Pow || imported.Len() != b.Len() {
+		t.Fatalf("imported %v blocks with %v", imported.Len(),
+			imported.PowHasher().Name())
+	}
+
+	// Any flipped bit is caught by the checksum.
+	corrupt := append([]byte{}, snapshot.Bytes()...)
+	corrupt[len(corrupt)/2] ^= 0x01
+	_, err = ImportBlockChain(bytes.NewReader(corrupt), clock)
+	if !errors.Is(err, ErrSnapshotChecksum) {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	// A modified block with a fixed up checksum fails validation.
+	blk := b.blocks[2]
+	blk.Data = []byte("Send 2 Decred to Alice")
+	snapshot.Reset()
+	if err := b.Export(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ImportBlockChain(bytes.NewReader(snapshot.Bytes()), clock)
+	var be *BlockError
+	if !errors.As(err, &be) || be.Height != 2 || be.Rule != RuleHash {
+		t.Fatalf("expected invalid block 2, got %v", err)
+	}
+
+	// A truncated snapshot is rejected.
+	payload := snapshot.Bytes()[:snapshot.Len()/2]
+	checksum := sha256.Sum256(payload)
+	truncated := append(append([]byte{}, payload...), checksum[:]...)
+	if _, err := ImportBlockChain(bytes.NewReader(truncated),
+		clock); err == nil {
+		t.Fatalf("truncated snapshot imported")
+	}
+	if _, err := ImportBlockChain(bytes.NewReader(nil), clock); err == nil {
+		t.Fatalf("empty snapshot imported")
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDataDirname = ".educoin"   // Data directory in the home directory
+	chainFilename      = "chain.json" // Blockchain file in the data directory
+
+	defaultRPCListen = "127.0.0.1:9109" // Default JSON-RPC listen address
+)
+
+// usageText describes the educoin subcommands.
+const usageText = `usage: educoin [flags] <command> [arguments]
+
+commands:
+  keygen                  create a new private key and address
+  address decode <addr>   decode a base58 or bech32 address
+  chain init [pow]        create a blockchain with a genesis block, pow is
+                          sha256 (default), double-sha256, blake256 or scrypt
+  chain append <data>     mine a block with data and append it
+  chain show              print every block
+  chain verify            validate every block against the consensus rules
+  chain export <file>     write a chain snapshot to file
+  chain import <file>     create the blockchain from a chain snapshot
+  serve [addr]            serve JSON-RPC on addr/rpc and the block explorer
+                          on addr (default 127.0.0.1:9109)
+
+flags:
+`
+
+// defaultDataDir returns the data directory in the home directory of the
+// user or the current directory if there is no home directory.
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultDataDirname
+	}
+	return filepath.Join(home, defaultDataDirname)
+}
+
+// node is the context of a single educoin invocation.
+type node struct {
+	dataDir string    // Network specific data directory
+	params  *Params   // Network parameters
+	clock   Clock     // Source of block timestamps
+	entropy io.Reader // Source of new keys
+	out     io.Writer // Command output
+}
+
+// chainFile returns the path of the blockchain file.
+func (n *node) chainFile() string {
+	return filepath.Join(n.dataDir, chainFilename)
+}
+
+// dump prints the block at height.
+func (n *node) dump(height int, b Block) {
+	fmt.Fprintf(n.out, "%v\n", strings.Repeat("=", 80))
+	fmt.Fprintf(n.out, "Height           : %v\n", height)
+	fmt.Fprintf(n.out, "Timestamp        : %v\n", time.Unix(b.Timestamp, 0))
+	fmt.Fprintf(n.out, "PreviousBlockHash: %x\n", b.PreviousBlockHash)
+	fmt.Fprintf(n.out, "Hash             : %x\n", b.Hash)
+	fmt.Fprintf(n.out, "Data             : %s\n", string(b.Data))
+	fmt.Fprintf(n.out, "Nonce            : %v\n", b.Nonce)
+}
+
+// keygen creates a new private key and prints it along with its address.
+func (n *node) keygen(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: keygen")
+	}
+	key, err := NewKey(n.entropy)
+	if err != nil {
+		return err
+	}
+	pk := NewPublicKey(key.Public())
+	a := pk.Address(n.params)
+	b32, err := a.Encode(FormatBech32m)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(n.out, "Private key: %x\n", key.D.FillBytes(make([]byte, 32)))
+	fmt.Fprintf(n.out, "Public key : %x\n", pk.Key())
+	fmt.Fprintf(n.out, "Address    : %v\n", a)
+	fmt.Fprintf(n.out, "Bech32m    : %v\n", b32)
+	return nil
+}
+
+// address executes the address subcommands.
+func (n *node) address(args []string) error {
+	if len(args) != 2 || args[0] != "decode" {
+		return fmt.Errorf("usage: address decode <addr>")
+	}
+	a, err := DecodeAddress(args[1])
+	if err != nil {
+		return err
+	}
+	b32, err := a.Encode(FormatBech32m)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(n.out, "Network   : %v\n", a.Net)
+	fmt.Fprintf(n.out, "Format    : %v\n", a.Format)
+	fmt.Fprintf(n.out, "Version   : %v\n", a.Version)
+	fmt.Fprintf(n.out, "PubKeyHash: %x\n", a.PubKeyHash)
+	fmt.Fprintf(n.out, "Checksum  : %x\n", a.Checksum)
+	fmt.Fprintf(n.out, "Base58    : %v\n", a)
+	fmt.Fprintf(n.out, "Bech32m   : %v\n", b32)
+	return nil
+}
+
+// chainInit creates a new blockchain with a genesis block. The optional
+// argument selects the proof-of-work algorithm.
+func (n *node) chainInit(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: chain init [pow]")
+	}
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	}
+	hasher, err := powHasherForName(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(n.chainFile()); err == nil {
+		return fmt.Errorf("blockchain already exists: %v", n.chainFile())
+	}
+	if err := os.MkdirAll(n.dataDir, 0700); err != nil {
+		return err
+	}
+	b, err := NewBlockChain(n.params, hasher, n.clock)
+	if err != nil {
+		return err
+	}
+	if err := b.Save(n.chainFile()); err != nil {
+		return err
+	}
+	genesis, err := b.Block(0)
+	if err != nil {
+		return err
+	}
+	n.dump(0, genesis)
+	return nil
+}
+
+// chainAppend mines a block that contains data and appends it to the
+// blockchain.
+func (n *node) chainAppend(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: chain append <data>")
+	}
+	b, err := LoadBlockChain(n.chainFile(), n.clock)
+	if err != nil {
+		return err
+	}
+	blk := b.PrepareBlock([]byte(strings.Join(args, " ")))
+	if err := b.Mine(blk); err != nil {
+		return err
+	}
+	if err := b.Append(blk); err != nil {
+		return err
+	}
+	if err := b.Save(n.chainFile()); err != nil {
+		return err
+	}
+	n.dump(b.Len()-1, *blk)
+	return nil
+}
+
+// chainShow prints every block.
+func (n *node) chainShow(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: chain show")
+	}
+	b, err := LoadBlockChain(n.chainFile(), n.clock)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < b.Len(); i++ {
+		blk, err := b.Block(i)
+		if err != nil {
+			return err
+		}
+		n.dump(i, blk)
+	}
+	return nil
+}
+
+// chainVerify validates every block against the consensus rules. The blocks
+// are read without verification so that corruption is reported per block.
+func (n *node) chainVerify(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: chain verify")
+	}
+	b, err := readChainFile(n.chainFile())
+	if err != nil {
+		return err
+	}
+	b.clock = n.clock
+	r := b.Validate()
+	invalid := make(map[int][]*BlockError)
+	for _, e := range r.Errors {
+		invalid[e.Height] = append(invalid[e.Height], e)
+	}
+	for i := 0; i < r.Blocks; i++ {
+		fmt.Fprintf(n.out, "Block %-6v valid: %v\n", i, len(invalid[i]) == 0)
+		for _, e := range invalid[i] {
+			fmt.Fprintf(n.out, "  %v: %v\n", e.Rule, e.Reason)
+		}
+	}
+	if !r.Valid() {
+		return fmt.Errorf("%v invalid blocks", len(r.InvalidHeights()))
+	}
+	return nil
+}
+
+// chainExport writes the blockchain to a chain snapshot.
+func (n *node) chainExport(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: chain export <file>")
+	}
+	b, err := LoadBlockChain(n.chainFile(), n.clock)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := b.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(n.out, "Exported %v blocks to %v\n", b.Len(), args[0])
+	return nil
+}
+
+// chainImport creates the blockchain from a chain snapshot. Every block in
+// the snapshot is validated.
+func (n *node) chainImport(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: chain import <file>")
+	}
+	if _, err := os.Stat(n.chainFile()); err == nil {
+		return fmt.Errorf("blockchain already exists: %v", n.chainFile())
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := ImportBlockChain(f, n.clock)
+	if err != nil {
+		return fmt.Errorf("%v: %v", args[0], err)
+	}
+	if b.Params() != n.params {
+		return fmt.Errorf("%v: snapshot is for %v", args[0], b.Params())
+	}
+	if err := os.MkdirAll(n.dataDir, 0700); err != nil {
+		return err
+	}
+	if err := b.Save(n.chainFile()); err != nil {
+		return err
+	}
+	fmt.Fprintf(n.out, "Imported %v blocks from %v\n", b.Len(), args[0])
+	return nil
+}
+
+// serve serves the blockchain via JSON-RPC on /rpc and the block explorer on
+// all other paths. Blocks that are submitted are saved to the data directory.
+func (n *node) serve(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: serve [addr]")
+	}
+	listen := defaultRPCListen
+	if len(args) == 1 {
+		listen = args[0]
+	}
+	b, err := LoadBlockChain(n.chainFile(), n.clock)
+	if err != nil {
+		return err
+	}
+	s := NewRPCServer(b, func() error {
+		return b.Save(n.chainFile())
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", s)
+	mux.Handle("/", NewExplorer(b, s))
+	fmt.Fprintf(n.out, "Serving %v JSON-RPC on %v/rpc\n", n.params, listen)
+	fmt.Fprintf(n.out, "Serving %v explorer on http://%v/\n", n.params, listen)
+	return http.ListenAndServe(listen, mux)
+}
+
+// chain executes the chain subcommands.
+func (n *node) chain(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: chain " +
+			"<init|append|show|verify|export|import>")
+	}
+	switch args[0] {
+	case "init":
+		return n.chainInit(args[1:])
+	case "append":
+		return n.chainAppend(args[1:])
+	case "show":
+		return n.chainShow(args[1:])
+	case "verify":
+		return n.chainVerify(args[1:])
+	case "export":
+		return n.chainExport(args[1:])
+	case "import":
+		return n.chainImport(args[1:])
+	}
+	return fmt.Errorf("unknown chain command: %v", args[0])
+}
+
+// run parses args and executes the requested command. Output is written to
+// out.
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("educoin", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprint(out, usageText)
+		fs.PrintDefaults()
+	}
+	dataDir := fs.String("datadir", defaultDataDir(), "data directory")
+	network := fs.String("net", MainNetParams.Name,
+		"network (mainnet, testnet or simnet)")
+	mockTime := fs.Int64("mocktime", 0,
+		"use this unix time instead of the system time")
+	seed := fs.Int64("seed", 0, "create keys from this seed instead of "+
+		"crypto/rand, simnet only since seeded keys can be guessed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	params, err := paramsForName(*network)
+	if err != nil {
+		return err
+	}
+	n := &node{
+		dataDir: filepath.Join(*dataDir, params.Name),
+		params:  params,
+		clock:   SystemClock,
+		entropy: rand.Reader,
+		out:     out,
+	}
+	if *mockTime != 0 {
+		n.clock = NewManualClock(time.Unix(*mockTime, 0))
+	}
+	if *seed != 0 {
+		if params != &SimNetParams {
+			return fmt.Errorf("-seed is only allowed on %v",
+				SimNetParams.Name)
+		}
+		n.entropy = mrand.New(mrand.NewSource(*seed))
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no command")
+	}
+	switch args[0] {
+	case "keygen":
+		return n.keygen(args[1:])
+	case "address":
+		return n.address(args[1:])
+	case "chain":
+		return n.chain(args[1:])
+	case "serve":
+		return n.serve(args[1:])
+	}
+	return fmt.Errorf("unknown command: %v", args[0])
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "educoin: %v\n", err)
+		os.Exit(1)
+	}
+}
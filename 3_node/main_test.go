@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// educoin runs the command line tool with a temporary data directory on
+// simnet and returns its output.
+func educoin(t *testing.T, dataDir string, args ...string) (string, error) {
+	var out bytes.Buffer
+	args = append([]string{"-datadir", dataDir, "-net", "simnet"}, args...)
+	err := run(args, &out)
+	t.Logf("educoin %v\n%v", strings.Join(args, " "), out.String())
+	return out.String(), err
+}
+
+func TestKeygen(t *testing.T) {
+	dataDir := t.TempDir()
+	out, err := educoin(t, dataDir, "keygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the generated address.
+	var addr string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Address") {
+			addr = strings.TrimSpace(strings.Split(line, ":")[1])
+		}
+	}
+	out, err = educoin(t, dataDir, "address", "decode", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Network   : simnet") {
+		t.Fatalf("invalid network")
+	}
+
+	if _, err := educoin(t, dataDir, "address", "decode", "x"); err == nil {
+		t.Fatalf("invalid address decoded")
+	}
+
+	// A seed replays key generation.
+	keygen := func(seed string) string {
+		out, err := educoin(t, dataDir, "-seed", seed, "keygen")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if keygen("1") != keygen("1") {
+		t.Fatalf("same seed, different keys")
+	}
+	if keygen("1") == keygen("2") {
+		t.Fatalf("different seed, same keys")
+	}
+
+	// Seeded keys can be guessed and never leave simnet.
+	for _, net := range []string{"mainnet", "testnet"} {
+		_, err := educoin(t, dataDir, "-net", net, "-seed", "1",
+			"keygen")
+		if err == nil {
+			t.Fatalf("seeded key created on %v", net)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	dataDir := t.TempDir()
+	if _, err := educoin(t, dataDir, "chain", "show"); err == nil {
+		t.Fatalf("show without chain")
+	}
+	if _, err := educoin(t, dataDir, "chain", "init"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := educoin(t, dataDir, "chain", "init"); err == nil {
+		t.Fatalf("chain initialized twice")
+	}
+	for _, data := range []string{"Send 1 Decred to Alice",
+		"Send 2 Decred to Bob"} {
+		_, err := educoin(t, dataDir, "chain", "append", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := educoin(t, dataDir, "chain", "show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Decred is play money!",
+		"Send 1 Decred to Alice", "Send 2 Decred to Bob",
+		"Height           : 2"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("missing from output: %v", s)
+		}
+	}
+	if _, err := educoin(t, dataDir, "chain", "verify"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chains are per network.
+	_, err = educoin(t, dataDir, "-net", "testnet", "chain", "show")
+	if err == nil {
+		t.Fatalf("testnet chain exists")
+	}
+
+	// Corrupt data and verify again.
+	filename := filepath.Join(dataDir, "simnet", chainFilename)
+	blob, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cf chainFile
+	if err := json.Unmarshal(blob, &cf); err != nil {
+		t.Fatal(err)
+	}
+	cf.Blocks[1].Data = []byte("Send 2 Decred to Alice")
+	blob, err = json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, blob, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, err = educoin(t, dataDir, "chain", "verify")
+	if err == nil {
+		t.Fatalf("corrupt chain verified")
+	}
+	if !strings.Contains(out, "Block 1      valid: false") {
+		t.Fatalf("corrupt block not reported")
+	}
+	if _, err := educoin(t, dataDir, "chain", "show"); err == nil {
+		t.Fatalf("corrupt chain loaded")
+	}
+}
+
+func TestChainExportImport(t *testing.T) {
+	dataDir := t.TempDir()
+	if _, err := educoin(t, dataDir, "chain", "init"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := educoin(t, dataDir, "chain", "append", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "chain.snapshot")
+	if _, err := educoin(t, dataDir, "chain", "export", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import into a fresh data directory.
+	other := t.TempDir()
+	out, err := educoin(t, other, "chain", "import", snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Imported 2 blocks") {
+		t.Fatalf("unexpected output: %v", out)
+	}
+	if _, err := educoin(t, other, "chain", "verify"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := educoin(t, other, "chain", "import", snapshot); err == nil {
+		t.Fatalf("imported over existing chain")
+	}
+}
+
+func TestChainMissingBlock(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	filename := filepath.Join(t.TempDir(), chainFilename)
+	tests := []string{
+		`{"network":"simnet","blocks":[null]}`,
+		`{"network":"simnet","blocks":[{},null]}`,
+	}
+	for _, test := range tests {
+		err := os.WriteFile(filename, []byte(test), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readChainFile(filename); err == nil {
+			t.Fatalf("%v: read", test)
+		}
+		_, err = LoadBlockChain(filename, clock)
+		if err == nil {
+			t.Fatalf("%v: loaded", test)
+		}
+		t.Logf("%v: %v", test, err)
+	}
+}
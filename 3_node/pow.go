@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/scrypt"
+	"github.com/decred/dcrd/crypto/blake256"
+)
+
+// The proof-of-work hash is what miners grind on. It does not have to be the
+// same hash that identifies a block. Bitcoin uses double SHA256 for both and
+// Decred uses BLAKE-256 for both, both of which are ASIC friendly: the hash is
+// cheap to compute in silicon. Litecoin uses scrypt for its proof-of-work,
+// which needs memory for every hash and was meant to keep mining on commodity
+// hardware, while blocks are still identified by their double SHA256 hash.
+//
+// Educoin always identifies blocks by the SHA256 hash of their contents and
+// lets every chain pick its proof-of-work algorithm.
+
+// PowHasher calculates the proof-of-work hash of a serialized block.
+type PowHasher interface {
+	// Name returns the name of the algorithm.
+	Name() string
+
+	// PowHash returns the 32 byte proof-of-work hash of blob.
+	PowHash(blob []byte) []byte
+}
+
+// sha256Hasher is single SHA256. Its proof-of-work hash is the same as the
+// block hash.
+type sha256Hasher struct{}
+
+// Name returns the name of the algorithm.
+func (sha256Hasher) Name() string {
+	return "sha256"
+}
+
+// PowHash returns sha256(blob).
+func (sha256Hasher) PowHash(blob []byte) []byte {
+	hash := sha256.Sum256(blob)
+	return hash[:]
+}
+
+// doubleSHA256Hasher is the Bitcoin proof-of-work.
+type doubleSHA256Hasher struct{}
+
+// Name returns the name of the algorithm.
+func (doubleSHA256Hasher) Name() string {
+	return "double-sha256"
+}
+
+// PowHash returns sha256(sha256(blob)).
+func (doubleSHA256Hasher) PowHash(blob []byte) []byte {
+	h0 := sha256.Sum256(blob)
+	h1 := sha256.Sum256(h0[:])
+	return h1[:]
+}
+
+// blake256Hasher is the Decred proof-of-work.
+type blake256Hasher struct{}
+
+// Name returns the name of the algorithm.
+func (blake256Hasher) Name() string {
+	return "blake256"
+}
+
+// PowHash returns blake256(blob).
+func (blake256Hasher) PowHash(blob []byte) []byte {
+	hash := blake256.Sum256(blob)
+	return hash[:]
+}
+
+// scryptHasher is the memory-hard Litecoin proof-of-work. Every hash needs
+// 128 * r * n bytes of memory.
+type scryptHasher struct {
+	n int // CPU and memory cost
+	r int // Block size
+	p int // Parallelization
+}
+
+// Name returns the name of the algorithm.
+func (scryptHasher) Name() string {
+	return "scrypt"
+}
+
+// PowHash returns scrypt(blob, blob) just like Litecoin does.
+func (s scryptHasher) PowHash(blob []byte) []byte {
+	hash, err := scrypt.Key(blob, blob, s.n, s.r, s.p, sha256.Size)
+	if err != nil {
+		// Only happens with invalid parameters.
+		panic(err)
+	}
+	return hash
+}
+
+var (
+	// SHA256Pow is single SHA256 proof-of-work. This is the algorithm of
+	// all previous lessons.
+	SHA256Pow PowHasher = sha256Hasher{}
+
+	// DoubleSHA256Pow is Bitcoin style double SHA256 proof-of-work.
+	DoubleSHA256Pow PowHasher = doubleSHA256Hasher{}
+
+	// Blake256Pow is Decred style BLAKE-256 proof-of-work.
+	Blake256Pow PowHasher = blake256Hasher{}
+
+	// ScryptPow is Litecoin style memory-hard scrypt proof-of-work.
+	ScryptPow PowHasher = scryptHasher{n: 1024, r: 1, p: 1}
+)
+
+// powHashers contains all proof-of-work algorithms.
+var powHashers = []PowHasher{SHA256Pow, DoubleSHA256Pow, Blake256Pow,
+	ScryptPow}
+
+// powHasherForName returns the proof-of-work algorithm called name. The empty
+// name selects SHA256Pow.
+func powHasherForName(name string) (PowHasher, error) {
+	if name == "" {
+		return SHA256Pow, nil
+	}
+	for _, h := range powHashers {
+		if h.Name() == name {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown proof-of-work algorithm: %v", name)
+}
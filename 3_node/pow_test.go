@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestPowHashVectors(t *testing.T) {
+	tests := []struct {
+		hasher PowHasher
+		want   string
+	}{
+		{
+			SHA256Pow,
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			DoubleSHA256Pow,
+			"5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456",
+		},
+		{
+			Blake256Pow,
+			"716f6e863f744b9ac22c97ec7b76ea5f5908bc5b2f67c61510bfc4751384ea7a",
+		},
+	}
+	for _, test := range tests {
+		got := hex.EncodeToString(test.hasher.PowHash(nil))
+		if got != test.want {
+			t.Fatalf("%v: got %v, want %v", test.hasher.Name(), got,
+				test.want)
+		}
+	}
+	if len(ScryptPow.PowHash(nil)) != 32 {
+		t.Fatalf("invalid scrypt hash length")
+	}
+}
+
+func TestPowHashers(t *testing.T) {
+	for _, hasher := range powHashers {
+		clock := NewManualClock(time.Unix(1600000000, 0))
+		start := time.Now()
+		b, err := NewBlockChain(&SimNetParams, hasher, clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mineBlocks(t, b, clock, 3)
+		if err := b.Validate().Err(); err != nil {
+			t.Fatalf("%v: %v", hasher.Name(), err)
+		}
+		t.Logf("%-13v mined %v blocks in %v", hasher.Name(), b.Len(),
+			time.Since(start))
+
+		// The block hash is the same for all algorithms.
+		tip, _, err := b.Tip()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tip.Hash, tip.calculateHash()) {
+			t.Fatalf("%v: invalid block hash", hasher.Name())
+		}
+		samePow := bytes.Equal(tip.Hash, tip.PowHash(hasher))
+		if samePow != (hasher == SHA256Pow) {
+			t.Fatalf("%v: unexpected proof-of-work hash",
+				hasher.Name())
+		}
+
+		h, err := powHasherForName(hasher.Name())
+		if err != nil || h != hasher {
+			t.Fatalf("%v: lookup failed: %v", hasher.Name(), err)
+		}
+	}
+	if _, err := powHasherForName("x11"); err == nil {
+		t.Fatalf("unknown algorithm found")
+	}
+}
+
+// BenchmarkPowHash compares the cost of a single proof-of-work hash over a
+// serialized block. The ASIC friendly hashes are orders of magnitude faster
+// than scrypt, which also allocates 128KiB per hash.
+func BenchmarkPowHash(b *testing.B) {
+	blob := make([]byte, 80)
+	for _, hasher := range powHashers {
+		b.Run(hasher.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hasher.PowHash(blob)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// The RPC server speaks JSON-RPC 2.0 over HTTP POST. Every request carries a
+// method name and positional parameters and every response carries either a
+// result or an error. Hashes and block data are hex encoded.
+
+const (
+	rpcVersion = "2.0" // JSON-RPC version
+
+	// Standard JSON-RPC error codes.
+	rpcErrParse          = -32700
+	rpcErrInvalidRequest = -32600
+	rpcErrMethodNotFound = -32601
+	rpcErrInvalidParams  = -32602
+
+	// Application error codes.
+	rpcErrBlockNotFound = -5
+	rpcErrBlockRejected = -25
+	rpcErrInternal      = -1
+
+	maxRPCRequestSize = 1 << 20 // Maximum size of a request body
+)
+
+// rpcRequest is a JSON-RPC request.
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+// RPCError is a JSON-RPC error.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+// rpcResponse is a JSON-RPC response.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCBlock is the RPC representation of a block.
+type RPCBlock struct {
+	Height            int    `json:"height"`            // Block height
+	Timestamp         int64  `json:"timestamp"`         // Unix timestamp
+	Data              string `json:"data"`              // Hex encoded data
+	PreviousBlockHash string `json:"previousblockhash"` // Hex encoded hash
+	Hash              string `json:"hash"`              // Hex encoded hash
+	Nonce             uint64 `json:"nonce"`             // PoW nonce
+}
+
+// newRPCBlock converts a block into its RPC representation.
+func newRPCBlock(height int, b Block) RPCBlock {
+	return RPCBlock{
+		Height:            height,
+		Timestamp:         b.Timestamp,
+		Data:              hex.EncodeToString(b.Data),
+		PreviousBlockHash: hex.EncodeToString(b.PreviousBlockHash),
+		Hash:              hex.EncodeToString(b.Hash),
+		Nonce:             b.Nonce,
+	}
+}
+
+// Block converts the RPC representation into a block.
+func (r RPCBlock) Block() (*Block, error) {
+	data, err := hex.DecodeString(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("data: %v", err)
+	}
+	previousBlockHash, err := hex.DecodeString(r.PreviousBlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("previousblockhash: %v", err)
+	}
+	hash, err := hex.DecodeString(r.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("hash: %v", err)
+	}
+	return &Block{
+		Timestamp:         r.Timestamp,
+		Data:              data,
+		PreviousBlockHash: previousBlockHash,
+		Hash:              hash,
+		Nonce:             r.Nonce,
+	}, nil
+}
+
+// RPCBlockTemplate is a block that is ready to be mined.
+type RPCBlockTemplate struct {
+	RPCBlock
+	Difficulty   uint   `json:"difficulty"`   // Required PoW difficulty
+	PowAlgorithm string `json:"powalgorithm"` // PoW hash algorithm
+}
+
+// rpcHandler is the signature of all RPC method handlers.
+type rpcHandler func(s *RPCServer, params []json.RawMessage) (interface{},
+	*RPCError)
+
+// rpcHandlers maps RPC method names to their handlers.
+var rpcHandlers = map[string]rpcHandler{
+	"getbestblockhash": handleGetBestBlockHash,
+	"getblock":         handleGetBlock,
+	"getblockcount":    handleGetBlockCount,
+	"getblocktemplate": handleGetBlockTemplate,
+	"submitblock":      handleSubmitBlock,
+}
+
+// RPCServer is the context of the JSON-RPC server. It implements
+// http.Handler.
+type RPCServer struct {
+	sync.Mutex // mutex to synchronize blockchain access
+
+	blockchain *Blockchain
+	save       func() error // Called after a block was appended, may be nil
+}
+
+// NewRPCServer returns a JSON-RPC server for blockchain. If save is not nil it
+// is called after every block that was appended via submitblock. A block is
+// only accepted if save succeeds.
+func NewRPCServer(blockchain *Blockchain, save func() error) *RPCServer {
+	return &RPCServer{
+		blockchain: blockchain,
+		save:       save,
+	}
+}
+
+// invalidParams returns an invalid parameters error.
+func invalidParams(format string, args ...interface{}) *RPCError {
+	return &RPCError{
+		Code:    rpcErrInvalidParams,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// handleGetBlockCount returns the number of blocks in the blockchain.
+func handleGetBlockCount(s *RPCServer,
+	params []json.RawMessage) (interface{}, *RPCError) {
+
+	if len(params) != 0 {
+		return nil, invalidParams("getblockcount takes no parameters")
+	}
+	return s.blockchain.Len(), nil
+}
+
+// handleGetBestBlockHash returns the hash of the last block.
+func handleGetBestBlockHash(s *RPCServer,
+	params []json.RawMessage) (interface{}, *RPCError) {
+
+	if len(params) != 0 {
+		return nil, invalidParams("getbestblockhash takes no parameters")
+	}
+	blk, _, err := s.blockchain.Tip()
+	if err != nil {
+		return nil, &RPCError{Code: rpcErrInternal, Message: err.Error()}
+	}
+	return hex.EncodeToString(blk.Hash), nil
+}
+
+// handleGetBlock returns the block with the provided height or hex encoded
+// hash.
+func handleGetBlock(s *RPCServer,
+	params []json.RawMessage) (interface{}, *RPCError) {
+
+	if len(params) != 1 {
+		return nil, invalidParams("getblock <height|hash>")
+	}
+
+	var height int
+	if err := json.Unmarshal(params[0], &height); err == nil {
+		blk, err := s.blockchain.Block(height)
+		if err != nil {
+			return nil, &RPCError{Code: rpcErrBlockNotFound,
+				Message: err.Error()}
+		}
+		return newRPCBlock(height, blk), nil
+	}
+
+	var h string
+	if err := json.Unmarshal(params[0], &h); err != nil {
+		return nil, invalidParams("height or hash expected")
+	}
+	hash, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, invalidParams("invalid hash: %v", err)
+	}
+	blk, height, err := s.blockchain.BlockByHash(hash)
+	if err != nil {
+		return nil, &RPCError{Code: rpcErrBlockNotFound,
+			Message: err.Error()}
+	}
+	return newRPCBlock(height, blk), nil
+}
+
+// handleGetBlockTemplate returns a block template that contains the hex
+// encoded data and links to the last block.
+func handleGetBlockTemplate(s *RPCServer,
+	params []json.RawMessage) (interface{}, *RPCError) {
+
+	if len(params) != 1 {
+		return nil, invalidParams("getblocktemplate <data>")
+	}
+	var h string
+	if err := json.Unmarshal(params[0], &h); err != nil {
+		return nil, invalidParams("hex encoded data expected")
+	}
+	data, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, invalidParams("invalid data: %v", err)
+	}
+	blk := s.blockchain.PrepareBlock(data)
+	return RPCBlockTemplate{
+		RPCBlock:     newRPCBlock(s.blockchain.Len(), *blk),
+		Difficulty:   s.blockchain.Params().Difficulty,
+		PowAlgorithm: s.blockchain.PowHasher().Name(),
+	}, nil
+}
+
+// handleSubmitBlock appends a mined block to the blockchain and returns its
+// height. A block that can't be saved is removed again so that the blockchain
+// in memory never runs ahead of the one on disk.
+func handleSubmitBlock(s *RPCServer,
+	params []json.RawMessage) (interface{}, *RPCError) {
+
+	if len(params) != 1 {
+		return nil, invalidParams("submitblock <block>")
+	}
+	var rb RPCBlock
+	if err := json.Unmarshal(params[0], &rb); err != nil {
+		return nil, invalidParams("block expected: %v", err)
+	}
+	blk, err := rb.Block()
+	if err != nil {
+		return nil, invalidParams("invalid block: %v", err)
+	}
+	if err := s.blockchain.Append(blk); err != nil {
+		return nil, &RPCError{Code: rpcErrBlockRejected,
+			Message: err.Error()}
+	}
+	if s.save != nil {
+		if err := s.save(); err != nil {
+			// Undo the append so that the client can resubmit the
+			// block once the problem is resolved.
+			s.blockchain.removeTip()
+			return nil, &RPCError{Code: rpcErrInternal,
+				Message: fmt.Sprintf("block not saved and "+
+					"removed again: %v", err)}
+		}
+	}
+	return s.blockchain.Len() - 1, nil
+}
+
+// handleRequest dispatches a single request.
+func (s *RPCServer) handleRequest(req *rpcRequest) (interface{}, *RPCError) {
+	if req.JSONRPC != rpcVersion || req.Method == "" {
+		return nil, &RPCError{Code: rpcErrInvalidRequest,
+			Message: "invalid request"}
+	}
+	handler, ok := rpcHandlers[req.Method]
+	if !ok {
+		return nil, &RPCError{
+			Code:    rpcErrMethodNotFound,
+			Message: fmt.Sprintf("method not found: %v", req.Method),
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	return handler(s, req.Params)
+}
+
+// ServeHTTP decodes a JSON-RPC request, executes it and writes the response.
+func (s *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reply := rpcResponse{JSONRPC: rpcVersion}
+	var req rpcRequest
+	body := http.MaxBytesReader(w, r.Body, maxRPCRequestSize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		reply.Error = &RPCError{Code: rpcErrParse, Message: err.Error()}
+	} else {
+		reply.ID = req.ID
+		reply.Result, reply.Error = s.handleRequest(&req)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reply); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
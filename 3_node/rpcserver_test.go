@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rpcCall executes method on the server at url and decodes the result into
+// result.
+func rpcCall(t *testing.T, url, method string, result interface{},
+	params ...interface{}) *RPCError {
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	req, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": rpcVersion,
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.Post(url, "application/json", bytes.NewReader(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	var reply struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.ID != 1 {
+		t.Fatalf("invalid id: %v", reply.ID)
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if err := json.Unmarshal(reply.Result, result); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func TestRPCServer(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := 0
+	s := httptest.NewServer(NewRPCServer(b, func() error {
+		saved++
+		return nil
+	}))
+	defer s.Close()
+
+	var count int
+	if err := rpcCall(t, s.URL, "getblockcount", &count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("invalid block count: %v", count)
+	}
+
+	// Mine a block from a template.
+	data := []byte("Send 1 Decred to Alice")
+	var tmpl RPCBlockTemplate
+	rerr := rpcCall(t, s.URL, "getblocktemplate", &tmpl,
+		hex.EncodeToString(data))
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if tmpl.Height != 1 || tmpl.Difficulty != SimNetParams.Difficulty {
+		t.Fatalf("invalid template: %+v", tmpl)
+	}
+	blk, err := tmpl.Block()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher, err := powHasherForName(tmpl.PowAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Mine(hasher, tmpl.Difficulty); err != nil {
+		t.Fatal(err)
+	}
+	var height int
+	rerr = rpcCall(t, s.URL, "submitblock", &height, newRPCBlock(1, *blk))
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if height != 1 || saved != 1 {
+		t.Fatalf("invalid height %v or saves %v", height, saved)
+	}
+
+	// Resubmitting the same block does not link.
+	rerr = rpcCall(t, s.URL, "submitblock", &height, newRPCBlock(1, *blk))
+	if rerr == nil || rerr.Code != rpcErrBlockRejected {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+
+	var best string
+	if err := rpcCall(t, s.URL, "getbestblockhash", &best); err != nil {
+		t.Fatal(err)
+	}
+	if best != hex.EncodeToString(blk.Hash) {
+		t.Fatalf("invalid best block hash: %v", best)
+	}
+
+	// Lookup by height and by hash.
+	var byHeight, byHash RPCBlock
+	if err := rpcCall(t, s.URL, "getblock", &byHeight, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := rpcCall(t, s.URL, "getblock", &byHash, best); err != nil {
+		t.Fatal(err)
+	}
+	if byHeight != byHash || byHash.Height != 1 {
+		t.Fatalf("block mismatch: %+v %+v", byHeight, byHash)
+	}
+	if d, _ := hex.DecodeString(byHash.Data); !bytes.Equal(d, data) {
+		t.Fatalf("invalid data: %v", byHash.Data)
+	}
+
+	// Errors
+	tests := []struct {
+		method string
+		params []interface{}
+		code   int
+	}{
+		{"getblock", []interface{}{2}, rpcErrBlockNotFound},
+		{"getblock", []interface{}{-1}, rpcErrBlockNotFound},
+		{"getblock", []interface{}{"00"}, rpcErrBlockNotFound},
+		{"getblock", []interface{}{"xyz"}, rpcErrInvalidParams},
+		{"getblock", nil, rpcErrInvalidParams},
+		{"getblockcount", []interface{}{1}, rpcErrInvalidParams},
+		{"getblocktemplate", []interface{}{"xyz"}, rpcErrInvalidParams},
+		{"submitblock", []interface{}{"xyz"}, rpcErrInvalidParams},
+		{"stop", nil, rpcErrMethodNotFound},
+	}
+	for _, test := range tests {
+		var result interface{}
+		err := rpcCall(t, s.URL, test.method, &result, test.params...)
+		if err == nil || err.Code != test.code {
+			t.Fatalf("%v %v: unexpected error: %v", test.method,
+				test.params, err)
+		}
+		t.Logf("%v %v: %v", test.method, test.params, err)
+	}
+}
+
+func TestRPCServerInvalidRequest(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewRPCServer(b, nil)
+
+	for _, body := range []string{"{", `{"jsonrpc":"1.0","method":"x"}`} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/",
+			bytes.NewBufferString(body))
+		s.ServeHTTP(w, r)
+		var reply rpcResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Error == nil {
+			t.Fatalf("%v: expected error", body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status: %v", w.Code)
+	}
+}
+
+func TestRPCServerSaveError(t *testing.T) {
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow,
+		NewManualClock(time.Unix(1600000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveErr := errors.New("disk full")
+	s := httptest.NewServer(NewRPCServer(b, func() error {
+		return saveErr
+	}))
+	defer s.Close()
+
+	blk := b.PrepareBlock([]byte("Send 1 Decred to Alice"))
+	if err := b.Mine(blk); err != nil {
+		t.Fatal(err)
+	}
+
+	// A block that can't be saved is not kept in memory either.
+	var height int
+	rerr := rpcCall(t, s.URL, "submitblock", &height, newRPCBlock(1, *blk))
+	if rerr == nil || rerr.Code != rpcErrInternal {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	t.Logf("%v", rerr)
+	if b.Len() != 1 || b.HasBlock(blk.Hash) {
+		t.Fatalf("unsaved block kept")
+	}
+
+	// The same block is accepted once saving works again.
+	saveErr = nil
+	rerr = rpcCall(t, s.URL, "submitblock", &height, newRPCBlock(1, *blk))
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if height != 1 || !b.HasBlock(blk.Hash) {
+		t.Fatalf("invalid height %v", height)
+	}
+}
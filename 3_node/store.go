@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// chainFile is the on disk representation of a Blockchain.
+type chainFile struct {
+	Network string   `json:"network"`       // Network name
+	Pow     string   `json:"pow,omitempty"` // Proof-of-work algorithm
+	Blocks  []*Block `json:"blocks"`        // All blocks, genesis first
+}
+
+// Save writes the blockchain to filename. The blockchain is written to a
+// temporary file that is renamed once complete so that an interrupted write
+// never leaves a partial chain behind.
+func (b Blockchain) Save(filename string) error {
+	blob, err := json.MarshalIndent(chainFile{
+		Network: b.params.Name,
+		Pow:     b.hasher.Name(),
+		Blocks:  b.blocks,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "chain")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// readChainFile reads a blockchain from filename without verifying its
+// blocks. Missing blocks are rejected since there is nothing to verify. Chains
+// that predate selectable proof-of-work algorithms use SHA256Pow.
+func readChainFile(filename string) (*Blockchain, error) {
+	blob, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cf chainFile
+	if err := json.Unmarshal(blob, &cf); err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	for i, blk := range cf.Blocks {
+		if blk == nil {
+			return nil, fmt.Errorf("%v: block %v missing",
+				filename, i)
+		}
+	}
+	params, err := paramsForName(cf.Network)
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := powHasherForName(cf.Pow)
+	if err != nil {
+		return nil, err
+	}
+	return &Blockchain{
+		params: params,
+		hasher: hasher,
+		blocks: cf.Blocks,
+	}, nil
+}
+
+// LoadBlockChain reads a blockchain from filename. Every block is appended one
+// by one and is therefore verified and linked to its parent. Clock is used to
+// validate and create timestamps.
+func LoadBlockChain(filename string, clock Clock) (*Blockchain, error) {
+	cf, err := readChainFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(cf.blocks) == 0 {
+		return nil, fmt.Errorf("%v: no genesis block", filename)
+	}
+	b := &Blockchain{params: cf.params, hasher: cf.hasher, clock: clock}
+	for _, blk := range cf.blocks {
+		if err := b.Append(blk); err != nil {
+			return nil, fmt.Errorf("%v: %v", filename, err)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Every block must obey a small set of consensus rules. Append refuses blocks
+// that break any of them, however a chain that was read from disk or modified
+// in memory may still contain invalid blocks. Validate walks the entire chain
+// and reports every violation instead of stopping at the first one.
+
+// Rule identifies a consensus rule.
+type Rule int
+
+const (
+	RuleHash            Rule = iota // Hash must match the block contents
+	RuleProofOfWork                 // Hash must satisfy the network difficulty
+	RuleLink                        // PreviousBlockHash must match the parent
+	RuleTimestamp                   // Timestamp must not precede median time
+	RuleFutureTimestamp             // Timestamp must not be too far ahead
+	RuleBlockSize                   // Serialized block must not be too large
+	RuleMissing                     // Block must be present
+)
+
+// String returns the human readable name of the rule.
+func (r Rule) String() string {
+	switch r {
+	case RuleHash:
+		return "hash"
+	case RuleProofOfWork:
+		return "proof-of-work"
+	case RuleLink:
+		return "link"
+	case RuleTimestamp:
+		return "timestamp"
+	case RuleFutureTimestamp:
+		return "future timestamp"
+	case RuleBlockSize:
+		return "block size"
+	case RuleMissing:
+		return "missing"
+	}
+	return fmt.Sprintf("unknown rule %d", int(r))
+}
+
+// BlockError describes a consensus rule violation of the block at Height.
+type BlockError struct {
+	Height int    // Height of the invalid block
+	Rule   Rule   // Violated rule
+	Reason string // Human readable description
+}
+
+// Error satisfies the error interface.
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %v: %v: %v", e.Height, e.Rule, e.Reason)
+}
+
+// ValidationReport is the result of validating a blockchain.
+type ValidationReport struct {
+	Blocks int           // Number of blocks that were validated
+	Errors []*BlockError // All violations ordered by height
+}
+
+// Valid returns true if no rule was violated.
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// InvalidHeights returns the heights of all invalid blocks in ascending order.
+func (r *ValidationReport) InvalidHeights() []int {
+	var heights []int
+	for _, e := range r.Errors {
+		if len(heights) == 0 || heights[len(heights)-1] != e.Height {
+			heights = append(heights, e.Height)
+		}
+	}
+	return heights
+}
+
+// Err returns nil if the chain is valid and an error that lists every
+// violation otherwise.
+func (r *ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	s := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		s = append(s, e.Error())
+	}
+	return fmt.Errorf("%v invalid blocks: %v", len(r.InvalidHeights()),
+		strings.Join(s, "; "))
+}
+
+// checkBlock returns all consensus rule violations of blk at height. All
+// blocks below height must already be part of the chain.
+//
+// Timestamps are checked against the median time past of the previous blocks
+// and against the clock. Unlike Bitcoin, which requires a timestamp strictly
+// after the median, a timestamp equal to the median is accepted because
+// lesson blocks are often mined within the same second.
+//
+// The size is checked first so that Append reports an oversized block as such
+// even if it was never mined. A missing block breaks no other rule since there
+// is nothing to check, however its child does not link.
+func (b *Blockchain) checkBlock(height int, blk *Block) []*BlockError {
+	var errs []*BlockError
+	fail := func(rule Rule, format string, args ...interface{}) {
+		errs = append(errs, &BlockError{
+			Height: height,
+			Rule:   rule,
+			Reason: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if blk == nil {
+		fail(RuleMissing, "block missing")
+		return errs
+	}
+	if size := blk.Size(); size > b.params.MaxBlockSize {
+		fail(RuleBlockSize, "%v bytes, maximum is %v", size,
+			b.params.MaxBlockSize)
+	}
+	if height > 0 && b.blocks[height-1] == nil {
+		fail(RuleLink, "previous block missing")
+	} else {
+		previousBlockHash := Empty[:]
+		if height > 0 {
+			previousBlockHash = b.blocks[height-1].Hash
+		}
+		if !bytes.Equal(previousBlockHash, blk.PreviousBlockHash) {
+			fail(RuleLink, "block does not link to previous "+
+				"block %x %x", previousBlockHash,
+				blk.PreviousBlockHash)
+		}
+	}
+	if !blk.Verify() {
+		fail(RuleHash, "hash does not match block contents")
+	} else if !blk.CheckProofOfWork(b.hasher, b.params.Difficulty) {
+		fail(RuleProofOfWork, "%v hash %x does not satisfy difficulty %v",
+			b.hasher.Name(), blk.PowHash(b.hasher), b.params.Difficulty)
+	}
+	if mtp := b.medianTimePast(height); blk.Timestamp < mtp {
+		fail(RuleTimestamp, "timestamp %v precedes median time past %v",
+			blk.Timestamp, mtp)
+	}
+	maxTimestamp := b.clock.Now().Add(maxFutureDrift).Unix()
+	if blk.Timestamp > maxTimestamp {
+		fail(RuleFutureTimestamp, "timestamp %v is more than %v ahead "+
+			"of the clock", blk.Timestamp, maxFutureDrift)
+	}
+	return errs
+}
+
+// Validate walks the blockchain from genesis and checks every block against
+// all consensus rules. All violations are reported; a corrupt block does not
+// stop validation of its descendants.
+func (b *Blockchain) Validate() *ValidationReport {
+	r := &ValidationReport{Blocks: len(b.blocks)}
+	for i, blk := range b.blocks {
+		r.Errors = append(r.Errors, b.checkBlock(i, blk)...)
+	}
+	return r
+}
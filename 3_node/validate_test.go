@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mineBlocks appends count mined blocks to b. If clock is not nil it is
+// advanced by a minute before every block.
+func mineBlocks(t *testing.T, b *Blockchain, clock *ManualClock, count int) {
+	for i := 0; i < count; i++ {
+		if clock != nil {
+			clock.Advance(time.Minute)
+		}
+		blk := b.PrepareBlock([]byte{byte(i)})
+		if err := b.Mine(blk); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Append(blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// relink links all blocks from height to the tip to their parent and mines
+// them again so that a modified block does not invalidate its descendants.
+func relink(t *testing.T, b *Blockchain, height int) {
+	for i := height; i < len(b.blocks); i++ {
+		b.blocks[i].PreviousBlockHash = b.blocks[i-1].Hash
+		if err := b.Mine(b.blocks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, clock, 5)
+
+	r := b.Validate()
+	if !r.Valid() || r.Err() != nil {
+		t.Fatalf("valid chain reported invalid: %v", r.Err())
+	}
+	if r.Blocks != 6 {
+		t.Fatalf("invalid number of blocks: %v", r.Blocks)
+	}
+
+	// Height 1: data no longer matches the hash.
+	b.blocks[1].Data = []byte("Send 2 Decred to Alice")
+
+	// Height 2: valid hash that does not satisfy the difficulty.
+	blk := b.blocks[2]
+	for blk.Nonce = 0; ; blk.Nonce++ {
+		blk.Hash = blk.calculateHash()
+		if !blk.CheckProofOfWork(b.PowHasher(),
+			b.Params().Difficulty) {
+			break
+		}
+	}
+	relink(t, b, 3)
+
+	// Height 4: points to genesis instead of its parent.
+	b.blocks[4].PreviousBlockHash = b.blocks[0].Hash
+	if err := b.Mine(b.blocks[4]); err != nil {
+		t.Fatal(err)
+	}
+	relink(t, b, 5)
+
+	// Height 5: timestamp before the median time past.
+	b.blocks[5].Timestamp = b.medianTimePast(5) - 1
+	if err := b.Mine(b.blocks[5]); err != nil {
+		t.Fatal(err)
+	}
+
+	r = b.Validate()
+	for _, e := range r.Errors {
+		t.Logf("%v", e)
+	}
+	if r.Valid() || r.Err() == nil {
+		t.Fatalf("invalid chain reported valid")
+	}
+	if !reflect.DeepEqual(r.InvalidHeights(), []int{1, 2, 4, 5}) {
+		t.Fatalf("invalid heights: %v", r.InvalidHeights())
+	}
+	want := []struct {
+		height int
+		rule   Rule
+	}{
+		{1, RuleHash},
+		{2, RuleProofOfWork},
+		{4, RuleLink},
+		{5, RuleTimestamp},
+	}
+	if len(r.Errors) != len(want) {
+		t.Fatalf("expected %v errors, got %v", len(want), len(r.Errors))
+	}
+	for i, w := range want {
+		if r.Errors[i].Height != w.height || r.Errors[i].Rule != w.rule {
+			t.Fatalf("error %v: got %v/%v, want %v/%v", i,
+				r.Errors[i].Height, r.Errors[i].Rule, w.height, w.rule)
+		}
+	}
+
+	// Append reports the first violation.
+	blk = b.PrepareBlock([]byte("orphan"))
+	blk.PreviousBlockHash = Empty[:]
+	if err := b.Mine(blk); err != nil {
+		t.Fatal(err)
+	}
+	err = b.Append(blk)
+	be, ok := err.(*BlockError)
+	if !ok || be.Rule != RuleLink || be.Height != 6 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateMissingBlock(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, clock, 3)
+
+	// Height 2 is missing, which leaves height 3 without a parent.
+	b.blocks[2] = nil
+	r := b.Validate()
+	for _, e := range r.Errors {
+		t.Logf("%v", e)
+	}
+	want := []struct {
+		height int
+		rule   Rule
+	}{
+		{2, RuleMissing},
+		{3, RuleLink},
+	}
+	if len(r.Errors) != len(want) {
+		t.Fatalf("expected %v errors, got %v", len(want), len(r.Errors))
+	}
+	for i, w := range want {
+		if r.Errors[i].Height != w.height || r.Errors[i].Rule != w.rule {
+			t.Fatalf("error %v: got %v/%v, want %v/%v", i,
+				r.Errors[i].Height, r.Errors[i].Rule, w.height, w.rule)
+		}
+	}
+
+	// Append refuses a missing block.
+	err = b.Append(nil)
+	be, ok := err.(*BlockError)
+	if !ok || be.Rule != RuleMissing || be.Height != 4 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTimestampRules(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	clock := NewManualClock(start)
+	b, err := NewBlockChain(&SimNetParams, SHA256Pow, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, b, clock, 12)
+
+	// The median of the last 11 blocks (minutes 2 through 12).
+	mtp := start.Add(7 * time.Minute)
+	if !b.MedianTimePast().Equal(mtp) {
+		t.Fatalf("invalid median time past: got %v, want %v",
+			b.MedianTimePast(), mtp)
+	}
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		rule      Rule
+		valid     bool
+	}{
+		{"before median", mtp.Add(-time.Second), RuleTimestamp, false},
+		{"median", mtp, 0, true},
+		{"before parent", start.Add(11 * time.Minute), 0, true},
+		{"max drift", clock.Now().Add(maxFutureDrift), 0, true},
+		{
+			"future",
+			clock.Now().Add(maxFutureDrift + time.Second),
+			RuleFutureTimestamp,
+			false,
+		},
+	}
+	for _, test := range tests {
+		blk := b.PrepareBlock([]byte(test.name))
+		blk.Timestamp = test.timestamp.Unix()
+		if err := b.Mine(blk); err != nil {
+			t.Fatal(err)
+		}
+		errs := b.checkBlock(b.Len(), blk)
+		if test.valid {
+			if len(errs) != 0 {
+				t.Fatalf("%v: unexpected error: %v", test.name, errs[0])
+			}
+			continue
+		}
+		if len(errs) != 1 || errs[0].Rule != test.rule {
+			t.Fatalf("%v: expected %v violation, got %v", test.name,
+				test.rule, errs)
+		}
+	}
+
+	// A clock that runs behind yields templates at the median time past.
+	clock.Set(start)
+	blk := b.PrepareBlock([]byte("slow clock"))
+	if blk.Timestamp != mtp.Unix() {
+		t.Fatalf("timestamp not raised to median time past: %v",
+			blk.Timestamp)
+	}
+}
+
+func TestBlockSize(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	params := SimNetParams
+	params.MaxBlockSize = 100
+	b, err := NewBlockChain(&params, SHA256Pow, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Timestamp, previous block hash and nonce take 48 bytes.
+	tests := []struct {
+		name  string
+		size  int
+		valid bool
+	}{
+		{"empty", 0, true},
+		{"maximum", params.MaxBlockSize - 48, true},
+		{"oversized", params.MaxBlockSize - 47, false},
+	}
+	for _, test := range tests {
+		clock.Advance(time.Minute)
+		blk := b.PrepareBlock(make([]byte, test.size))
+		t.Logf("%v: %v bytes", test.name, blk.Size())
+		err := b.Mine(blk)
+		if test.valid {
+			if err != nil {
+				t.Fatalf("%v: %v", test.name, err)
+			}
+			if err := b.Append(blk); err != nil {
+				t.Fatalf("%v: %v", test.name, err)
+			}
+			continue
+		}
+		var be *BlockError
+		if !errors.As(err, &be) || be.Rule != RuleBlockSize {
+			t.Fatalf("%v: mined: %v", test.name, err)
+		}
+
+		// A block that was mined elsewhere is rejected as well.
+		err = blk.Mine(b.PowHasher(), params.Difficulty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.Append(blk)
+		if !errors.As(err, &be) || be.Rule != RuleBlockSize {
+			t.Fatalf("%v: appended: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/big"
+)
+
+// AUTOGENERATED by genalphabet.go; do not edit.
+
+const (
+	// alphabet is the modified base58 alphabet used by Bitcoin.
+	alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	alphabetIdx0 = '1'
+)
+
+var b58 = [256]byte{
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 0, 1, 2, 3, 4, 5, 6,
+	7, 8, 255, 255, 255, 255, 255, 255,
+	255, 9, 10, 11, 12, 13, 14, 15,
+	16, 255, 17, 18, 19, 20, 21, 255,
+	22, 23, 24, 25, 26, 27, 28, 29,
+	30, 31, 32, 255, 255, 255, 255, 255,
+	255, 33, 34, 35, 36, 37, 38, 39,
+	40, 41, 42, 43, 255, 44, 45, 46,
+	47, 48, 49, 50, 51, 52, 53, 54,
+	55, 56, 57, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	255, 255, 255, 255, 255, 255, 255, 255,
+}
+
+var bigRadix = big.NewInt(58)
+var bigZero = big.NewInt(0)
+
+const (
+	// radix58 is the largest power of 58 that fits in a uint32 limb. It is
+	// used to process 5 base58 digits at a time.
+	radix58       = 58 * 58 * 58 * 58 * 58
+	radix58Digits = 5
+
+	// limbBits is the number of bits in a binary limb.
+	limbBits  = 32
+	limbBytes = limbBits / 8
+)
+
+// checkAlphabet returns an InvalidCharacterError for the first character in b
+// that is not part of the alphabet.
+func checkAlphabet(b string) error {
+	for i := 0; i < len(b); i++ {
+		if b58[b[i]] == 255 {
+			return InvalidCharacterError{Position: i, Char: b[i]}
+		}
+	}
+	return nil
+}
+
+// Decode decodes a modified base58 string to a byte slice. It returns an
+// empty slice when b contains an invalid character; use DecodeErr in order to
+// tell invalid input apart from empty input.
+func Decode(b string) []byte {
+	val, err := DecodeErr(b)
+	if err != nil {
+		return []byte("")
+	}
+	return val
+}
+
+// DecodeErr decodes a modified base58 string to a byte slice. It returns an
+// InvalidCharacterError when b contains a character that is not part of the
+// alphabet.
+//
+// The number is accumulated in little endian uint32 limbs. Every iteration
+// multiplies the limbs by 58^n and adds n digits at once which avoids the
+// allocations of math/big.
+func DecodeErr(b string) ([]byte, error) {
+	if err := checkAlphabet(b); err != nil {
+		return nil, err
+	}
+
+	var numZeros int
+	for numZeros = 0; numZeros < len(b); numZeros++ {
+		if b[numZeros] != alphabetIdx0 {
+			break
+		}
+	}
+
+	// Every base58 digit carries less than 6 bits.
+	limbs := make([]uint32, 0, (len(b)-numZeros)*6/limbBits+1)
+	for i := numZeros; i < len(b); {
+		// Consume up to 5 digits; the first group aligns the rest.
+		n := (len(b) - i) % radix58Digits
+		if n == 0 {
+			n = radix58Digits
+		}
+		var digits, mul uint64 = 0, 1
+		for k := 0; k < n; k++ {
+			digits = digits*58 + uint64(b58[b[i+k]])
+			mul *= 58
+		}
+		i += n
+
+		carry := digits
+		for j := range limbs {
+			carry += uint64(limbs[j]) * mul
+			limbs[j] = uint32(carry)
+			carry >>= limbBits
+		}
+		if carry > 0 {
+			limbs = append(limbs, uint32(carry))
+		}
+	}
+
+	// Emit big endian bytes without leading zeros.
+	val := make([]byte, numZeros, numZeros+len(limbs)*limbBytes)
+	leading := true
+	for j := len(limbs) - 1; j >= 0; j-- {
+		for k := limbBytes - 1; k >= 0; k-- {
+			c := byte(limbs[j] >> uint(8*k))
+			if leading && c == 0 {
+				continue
+			}
+			leading = false
+			val = append(val, c)
+		}
+	}
+
+	return val, nil
+}
+
+// Encode encodes a byte slice to a modified base58 string.
+//
+// The number is accumulated in little endian uint32 limbs that each hold 5
+// base58 digits. Every iteration multiplies the limbs by 2^32 and adds 4 input
+// bytes at once which avoids the allocations of math/big.
+func Encode(b []byte) string {
+	var numZeros int
+	for numZeros = 0; numZeros < len(b); numZeros++ {
+		if b[numZeros] != 0 {
+			break
+		}
+	}
+
+	// Every limb holds more than 29 bits.
+	limbs := make([]uint32, 0, (len(b)-numZeros)*8/29+1)
+	for i := numZeros; i < len(b); {
+		// Consume up to 4 bytes; the first group aligns the rest.
+		n := (len(b) - i) % limbBytes
+		if n == 0 {
+			n = limbBytes
+		}
+		var word uint64
+		for k := 0; k < n; k++ {
+			word = word<<8 | uint64(b[i+k])
+		}
+		shift := uint(8 * n)
+		i += n
+
+		carry := word
+		for j := range limbs {
+			carry += uint64(limbs[j]) << shift
+			limbs[j] = uint32(carry % radix58)
+			carry /= radix58
+		}
+		for carry > 0 {
+			limbs = append(limbs, uint32(carry%radix58))
+			carry /= radix58
+		}
+	}
+
+	// Emit digits least significant first.
+	answer := make([]byte, 0, len(limbs)*radix58Digits+numZeros)
+	for j, limb := range limbs {
+		for k := 0; k < radix58Digits; k++ {
+			if j == len(limbs)-1 && limb == 0 {
+				break
+			}
+			answer = append(answer, alphabet[limb%58])
+			limb /= 58
+		}
+	}
+
+	// leading zero bytes
+	for i := 0; i < numZeros; i++ {
+		answer = append(answer, alphabetIdx0)
+	}
+
+	// reverse
+	alen := len(answer)
+	for i := 0; i < alen/2; i++ {
+		answer[i], answer[alen-1-i] = answer[alen-1-i], answer[i]
+	}
+
+	return string(answer)
+}
+
+// decodeBig is the math/big reference implementation of DecodeErr. It is
+// easier to follow but quadratic and allocation heavy.
+func decodeBig(b string) ([]byte, error) {
+	if err := checkAlphabet(b); err != nil {
+		return nil, err
+	}
+
+	answer := big.NewInt(0)
+	j := big.NewInt(1)
+
+	scratch := new(big.Int)
+	for i := len(b) - 1; i >= 0; i-- {
+		tmp := b58[b[i]]
+		scratch.SetInt64(int64(tmp))
+		scratch.Mul(j, scratch)
+		answer.Add(answer, scratch)
+		j.Mul(j, bigRadix)
+	}
+
+	tmpval := answer.Bytes()
+
+	var numZeros int
+	for numZeros = 0; numZeros < len(b); numZeros++ {
+		if b[numZeros] != alphabetIdx0 {
+			break
+		}
+	}
+	flen := numZeros + len(tmpval)
+	val := make([]byte, flen)
+	copy(val[numZeros:], tmpval)
+
+	return val, nil
+}
+
+// encodeBig is the math/big reference implementation of Encode.
+func encodeBig(b []byte) string {
+	x := new(big.Int)
+	x.SetBytes(b)
+
+	answer := make([]byte, 0, len(b)*136/100)
+	for x.Cmp(bigZero) > 0 {
+		mod := new(big.Int)
+		x.DivMod(x, bigRadix, mod)
+		answer = append(answer, alphabet[mod.Int64()])
+	}
+
+	// leading zero bytes
+	for _, i := range b {
+		if i != 0 {
+			break
+		}
+		answer = append(answer, alphabetIdx0)
+	}
+
+	// reverse
+	alen := len(answer)
+	for i := 0; i < alen/2; i++ {
+		answer[i], answer[alen-1-i] = answer[alen-1-i], answer[i]
+	}
+
+	return string(answer)
+}
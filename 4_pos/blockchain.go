@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var Empty [sha256.Size]byte // All zero sha256 value
+
+const (
+	// medianTimeBlocks is the number of previous blocks that are used to
+	// calculate the median time past.
+	medianTimeBlocks = 11
+
+	// maxFutureDrift is how far a block timestamp may be ahead of the
+	// clock.
+	maxFutureDrift = 2 * time.Hour
+)
+
+// encodeUint64 encodes a uint64 to big endian notation. This code uses big
+// endian in order to make the resulting values more readable for humans.
+func encodeUint64(x uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, x)
+	return b
+}
+
+// Block represents a single block in the blockchain. It is linked to the prior
+// block via the PreviousBlockHash. In addition to data a block carries ticket
+// purchases and the votes of the tickets that were selected to vote on the
+// previous block.
+type Block struct {
+	Timestamp         int64     // Timestamp block was mined
+	Data              []byte    // Blockchain data
+	Tickets           []*Ticket // Ticket purchases
+	Votes             []*Vote   // Votes on the previous block
+	PreviousBlockHash []byte    // Previous block hash in order link blocks
+	Hash              []byte    // PoW hash of this block
+	Nonce             uint64    // Nonce used to calculate Hash
+}
+
+// NewBlock returns a block that is linked to previousBlockHash and stamped
+// with the current time of clock.
+func NewBlock(clock Clock, data, previousBlockHash []byte) Block {
+	timestamp := clock.Now().Unix()
+	return Block{
+		Timestamp:         timestamp,
+		Data:              data,
+		PreviousBlockHash: previousBlockHash,
+	}
+}
+
+// StakeRoot returns the hash that commits the block to its tickets and votes.
+func (b Block) StakeRoot() []byte {
+	h := sha256.New()
+	h.Write(encodeUint64(uint64(len(b.Tickets))))
+	for _, t := range b.Tickets {
+		h.Write(t.Hash())
+	}
+	h.Write(encodeUint64(uint64(len(b.Votes))))
+	for _, v := range b.Votes {
+		h.Write(v.Hash())
+	}
+	return h.Sum(nil)
+}
+
+// header returns everything that is hashed except for the nonce.
+func (b Block) header() []byte {
+	t := encodeUint64(uint64(b.Timestamp))
+	return bytes.Join([][]byte{t, b.Data, b.StakeRoot(),
+		b.PreviousBlockHash}, []byte{})
+}
+
+// calculateHash returns the hash of timestamp, data, stake root, previous
+// block hash and nonce.
+func (b Block) calculateHash() []byte {
+	hash := sha256.Sum256(append(b.header(), encodeUint64(b.Nonce)...))
+	return hash[:]
+}
+
+// Verify ensures that the block is valid by hashing its contents and nonce.
+func (b Block) Verify() bool {
+	return bytes.Equal(b.calculateHash(), b.Hash)
+}
+
+// powTarget returns the value that a block hash must be below in order to
+// satisfy difficulty.
+func powTarget(difficulty uint) *big.Int {
+	target := big.NewInt(1)
+	return target.Lsh(target, uint(256-difficulty))
+}
+
+// CheckProofOfWork returns true if the block hash satisfies difficulty. It
+// does not verify that the hash matches the block contents.
+func (b Block) CheckProofOfWork(difficulty uint) bool {
+	return new(big.Int).SetBytes(b.Hash).Cmp(powTarget(difficulty)) == -1
+}
+
+// Mine attempts to mine the block within the provided range. Tickets and votes
+// must be added before mining because they are committed to by the hash.
+func (b *Block) Mine(difficulty uint) error {
+	target := powTarget(difficulty)
+	header := b.header()
+	n := make([]byte, 8)
+	bi := big.Int{}
+	for i := uint64(0); i < math.MaxInt64; i++ {
+		binary.BigEndian.PutUint64(n, i)
+		hash := sha256.Sum256(bytes.Join([][]byte{header, n},
+			[]byte{}))
+		bi.SetBytes(hash[:])
+		if bi.Cmp(target) == -1 {
+			b.Hash = hash[:]
+			b.Nonce = i
+			return nil
+		}
+	}
+	return fmt.Errorf("no solution for block")
+}
+
+// Blockchain is the blockchain context that houses an array of blocks.
+type Blockchain struct {
+	params *Params // Network parameters
+	clock  Clock   // Source of the current time
+	blocks []*Block
+	index  map[string]int // Block hash to height
+	pool   *TicketPool    // Tickets that have not voted yet
+}
+
+// checkBlock returns the first consensus rule that blk at height violates.
+func (b *Blockchain) checkBlock(height int, blk *Block) error {
+	previousBlockHash := Empty[:]
+	if height > 0 {
+		previousBlockHash = b.blocks[height-1].Hash
+	}
+	if !bytes.Equal(previousBlockHash, blk.PreviousBlockHash) {
+		return fmt.Errorf("block does not link to previous block %x %x",
+			previousBlockHash, blk.PreviousBlockHash)
+	}
+	if !blk.Verify() {
+		return fmt.Errorf("can't append invalid block")
+	}
+	if !blk.CheckProofOfWork(b.params.Difficulty) {
+		return fmt.Errorf("hash %x does not satisfy difficulty %v",
+			blk.Hash, b.params.Difficulty)
+	}
+	if mtp := b.medianTimePast(height); blk.Timestamp < mtp {
+		return fmt.Errorf("timestamp %v precedes median time past %v",
+			blk.Timestamp, mtp)
+	}
+	maxTimestamp := b.clock.Now().Add(maxFutureDrift).Unix()
+	if blk.Timestamp > maxTimestamp {
+		return fmt.Errorf("timestamp %v is more than %v ahead of the "+
+			"clock", blk.Timestamp, maxFutureDrift)
+	}
+	return b.checkStake(height, blk)
+}
+
+// Append adds a block, if valid, to the end of the blockchain. Besides proof
+// of work, a block at or above the stake validation height must carry votes
+// from a majority of the tickets that were selected to vote on its parent.
+func (b *Blockchain) Append(blk *Block) error {
+	height := len(b.blocks)
+	if err := b.checkBlock(height, blk); err != nil {
+		return err
+	}
+	b.connectStake(height, blk)
+	if b.index == nil {
+		b.index = make(map[string]int)
+	}
+	b.index[string(blk.Hash)] = height
+	b.blocks = append(b.blocks, blk)
+	return nil
+}
+
+// PrepareBlock returns a block template based on the current height of the
+// blockchain. The timestamp is raised to the median time past if the clock is
+// behind it so that the block is acceptable.
+func (b *Blockchain) PrepareBlock(data []byte) *Block {
+	var previousBlockHash []byte
+	if len(b.blocks) == 0 {
+		// Genesis
+		previousBlockHash = Empty[:]
+	} else {
+		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
+	}
+	blk := NewBlock(b.clock, data, previousBlockHash)
+	if mtp := b.medianTimePast(len(b.blocks)); blk.Timestamp < mtp {
+		blk.Timestamp = mtp
+	}
+	return &blk
+}
+
+// Block returns a copy of the block at the specified block height.
+func (b Blockchain) Block(block int) (Block, error) {
+	if block < 0 || block >= len(b.blocks) {
+		return Block{}, fmt.Errorf("invalid block: %v", block)
+	}
+	return *b.blocks[block], nil
+}
+
+// BlockByHash returns a copy of the block with the specified hash along with
+// its height.
+func (b Blockchain) BlockByHash(hash []byte) (Block, int, error) {
+	height, ok := b.index[string(hash)]
+	if !ok {
+		return Block{}, 0, fmt.Errorf("block not found: %x", hash)
+	}
+	return *b.blocks[height], height, nil
+}
+
+// Tip returns a copy of the last block along with its height.
+func (b Blockchain) Tip() (Block, int, error) {
+	if len(b.blocks) == 0 {
+		return Block{}, 0, fmt.Errorf("empty blockchain")
+	}
+	height := len(b.blocks) - 1
+	return *b.blocks[height], height, nil
+}
+
+// medianTimePast returns the median timestamp of the up to medianTimeBlocks
+// blocks that precede height.
+func (b Blockchain) medianTimePast(height int) int64 {
+	start := height - medianTimeBlocks
+	if start < 0 {
+		start = 0
+	}
+	if height <= start {
+		return 0
+	}
+	timestamps := make([]int64, 0, height-start)
+	for _, blk := range b.blocks[start:height] {
+		timestamps = append(timestamps, blk.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2]
+}
+
+// Len returns the current blockchain height.
+func (b Blockchain) Len() int {
+	return len(b.blocks)
+}
+
+// Params returns the network parameters of the blockchain.
+func (b Blockchain) Params() *Params {
+	return b.params
+}
+
+// NewBlockChain returns a blockchain context that has a genesis block. The
+// genesis data and the difficulty are taken from the network parameters. All
+// timestamps are taken from clock.
+func NewBlockChain(params *Params, clock Clock) (*Blockchain, error) {
+	b := &Blockchain{
+		params: params,
+		clock:  clock,
+		pool:   NewTicketPool(params.TicketMaturity),
+	}
+	blk := b.PrepareBlock(params.GenesisData)
+	err := blk.Mine(params.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+	err = b.Append(blk)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
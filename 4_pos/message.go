@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// Messages are signed with recoverable ECDSA signatures. In addition to r and
+// s the signature carries a recovery id that identifies which of the (up to
+// four) public keys that satisfy the signature equation was used. The verifier
+// recovers the public key from the signature and compares its hash with the
+// address of the signer. A signature is therefore verified with nothing more
+// than an address.
+
+const (
+	// messageMagic is prepended to every message before hashing it. This
+	// domain separation ensures that a signed message can never be
+	// mistaken for a signed transaction or block.
+	messageMagic = "Educoin Signed Message:\n"
+
+	scalarSize           = 32               // Size of r and s
+	MessageSignatureSize = 1 + 2*scalarSize // [header][r][s]
+	recoveryHeader       = 27               // Header of recovery id 0
+	maxRecoveryID        = 3                // Largest recovery id
+)
+
+// writeVarBytes writes the varint length of b followed by b.
+func writeVarBytes(w *bytes.Buffer, b []byte) {
+	var l [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(l[:], uint64(len(b)))
+	w.Write(l[:n])
+	w.Write(b)
+}
+
+// MessageHash returns the domain separated hash of message. The process is
+// sha256(sha256(varbytes(magic)+varbytes(message))).
+func MessageHash(message []byte) []byte {
+	var b bytes.Buffer
+	writeVarBytes(&b, []byte(messageMagic))
+	writeVarBytes(&b, message)
+	h0 := sha256.Sum256(b.Bytes())
+	h1 := sha256.Sum256(h0[:])
+	return h1[:]
+}
+
+// SignMessage returns a recoverable signature of message. The signature is
+// laid out as [27+recovery id][r][s].
+func (p PrivateKey) SignMessage(message []byte) ([]byte, error) {
+	hash := MessageHash(message)
+	r, s := signRFC6979(&p.PrivateKey, hash)
+
+	// Find the recovery id that yields our public key.
+	signature := make([]byte, MessageSignatureSize)
+	r.FillBytes(signature[1 : 1+scalarSize])
+	s.FillBytes(signature[1+scalarSize:])
+	for id := 0; id <= maxRecoveryID; id++ {
+		signature[0] = byte(recoveryHeader + id)
+		pk, err := RecoverPublicKey(hash, signature)
+		if err != nil {
+			continue
+		}
+		if pk.X.Cmp(p.X) == 0 && pk.Y.Cmp(p.Y) == 0 {
+			return signature, nil
+		}
+	}
+	return nil, fmt.Errorf("no recovery id for signature")
+}
+
+// decompressPoint returns the point on curve with x coordinate x and the
+// requested y parity. It relies on p = 3 mod 4 in order to calculate the
+// square root as (y^2)^((p+1)/4).
+func decompressPoint(curve elliptic.Curve, x *big.Int, odd bool) (*big.Int,
+	error) {
+
+	params := curve.Params()
+
+	// y^2 = x^3 - 3x + b
+	y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y2.Sub(y2, threeX)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+
+	e := new(big.Int).Add(params.P, big.NewInt(1))
+	e.Rsh(e, 2)
+	y := new(big.Int).Exp(y2, e, params.P)
+	if new(big.Int).Exp(y, big.NewInt(2), params.P).Cmp(y2) != 0 {
+		return nil, fmt.Errorf("x is not on the curve")
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(params.P, y)
+	}
+	return y, nil
+}
+
+// RecoverPublicKey recovers the public key that created signature over the
+// sha256 sized hash.
+// The public key is calculated as Q = r^-1(sR - eG) where R is the point that
+// is identified by r and the recovery id.
+func RecoverPublicKey(hash, signature []byte) (*PublicKey, error) {
+	if len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid hash length")
+	}
+	if len(signature) != MessageSignatureSize {
+		return nil, fmt.Errorf("invalid signature length")
+	}
+	id := int(signature[0]) - recoveryHeader
+	if id < 0 || id > maxRecoveryID {
+		return nil, fmt.Errorf("invalid recovery id")
+	}
+	curve := elliptic.P256()
+	params := curve.Params()
+	r := new(big.Int).SetBytes(signature[1 : 1+scalarSize])
+	s := new(big.Int).SetBytes(signature[1+scalarSize:])
+	if r.Sign() == 0 || r.Cmp(params.N) >= 0 ||
+		s.Sign() == 0 || s.Cmp(params.N) >= 0 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	// R.x is r or, for recovery ids 2 and 3, r+N.
+	x := new(big.Int).Set(r)
+	if id >= 2 {
+		x.Add(x, params.N)
+		if x.Cmp(params.P) >= 0 {
+			return nil, fmt.Errorf("invalid recovery id")
+		}
+	}
+	y, err := decompressPoint(curve, x, id&1 == 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Q = r^-1(sR - eG)
+	e := new(big.Int).SetBytes(hash)
+	e.Neg(e)
+	e.Mod(e, params.N)
+	rInv := new(big.Int).ModInverse(r, params.N)
+	sx, sy := curve.ScalarMult(x, y, s.Bytes())
+	ex, ey := curve.ScalarBaseMult(e.Bytes())
+	qx, qy := curve.Add(sx, sy, ex, ey)
+	qx, qy = curve.ScalarMult(qx, qy, rInv.Bytes())
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	pk := &PublicKey{ecdsa.PublicKey{Curve: curve, X: qx, Y: qy}}
+	if !ecdsa.Verify(&pk.PublicKey, hash, r, s) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	return pk, nil
+}
+
+// VerifyMessage verifies that signature over message was created by the
+// private key that belongs to address a. It recovers the public key from the
+// signature and compares its PubKeyHash with the one in the address.
+func VerifyMessage(a *Address, message, signature []byte) bool {
+	pk, err := RecoverPublicKey(MessageHash(message), signature)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(pk.Hash(), a.PubKeyHash)
+}
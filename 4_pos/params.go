@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Params defines the parameters that differ between educoin networks. Each
+// network uses its own address version prefix so that an address can never be
+// mistaken for one that belongs to another network.
+type Params struct {
+	Name             string // Human readable network name
+	PubKeyHashAddrID byte   // Address version prefix
+	Bech32HRP        string // Human-readable part of bech32 addresses
+	GenesisData      []byte // Data stored in the genesis block
+	Difficulty       uint   // Static difficulty for PoW calculation
+
+	// Proof-of-stake parameters.
+	TicketPrice           uint64 // Minimum price of a ticket
+	TicketMaturity        int    // Blocks before a ticket enters the pool
+	TicketsPerBlock       int    // Tickets selected to vote on each block
+	StakeValidationHeight int    // First height that requires votes
+}
+
+var (
+	// MainNetParams are the parameters of the main network.
+	MainNetParams = Params{
+		Name:             "mainnet",
+		PubKeyHashAddrID: 0x00,
+		Bech32HRP:        "ec",
+		GenesisData:      []byte("Decred is money!"),
+		Difficulty:       16,
+
+		TicketPrice:           2 * 1e8,
+		TicketMaturity:        256,
+		TicketsPerBlock:       5,
+		StakeValidationHeight: 4096,
+	}
+
+	// TestNetParams are the parameters of the test network.
+	TestNetParams = Params{
+		Name:             "testnet",
+		PubKeyHashAddrID: 0x6f,
+		Bech32HRP:        "tec",
+		GenesisData:      []byte("Decred is test money!"),
+		Difficulty:       12,
+
+		TicketPrice:           2 * 1e8,
+		TicketMaturity:        16,
+		TicketsPerBlock:       5,
+		StakeValidationHeight: 768,
+	}
+
+	// SimNetParams are the parameters of the simulation network. The
+	// difficulty is low enough to mine blocks instantly and votes are
+	// required after a handful of blocks.
+	SimNetParams = Params{
+		Name:             "simnet",
+		PubKeyHashAddrID: 0x3f,
+		Bech32HRP:        "sec",
+		GenesisData:      []byte("Decred is play money!"),
+		Difficulty:       8,
+
+		TicketPrice:           1e8,
+		TicketMaturity:        4,
+		TicketsPerBlock:       5,
+		StakeValidationHeight: 16,
+	}
+)
+
+// networks contains all known networks.
+var networks = []*Params{&MainNetParams, &TestNetParams, &SimNetParams}
+
+// String returns the name of the network.
+func (p Params) String() string {
+	return p.Name
+}
+
+// paramsForAddrID returns the network that uses address version id.
+func paramsForAddrID(id byte) (*Params, error) {
+	for _, p := range networks {
+		if p.PubKeyHashAddrID == id {
+			return p, nil
+		}
+	}
+	return nil, InvalidVersionError{Version: id}
+}
+
+// paramsForHRP returns the network that uses bech32 human-readable part hrp.
+func paramsForHRP(hrp string) (*Params, error) {
+	for _, p := range networks {
+		if p.Bech32HRP == hrp {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown human-readable part: %v", hrp)
+}
+
+// paramsForName returns the network called name.
+func paramsForName(name string) (*Params, error) {
+	for _, p := range networks {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown network: %v", name)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Proof-of-work alone lets whoever controls the most hash power decide which
+// blocks make it into the chain. Decred adds a second layer: stakeholders buy
+// tickets and a handful of tickets is pseudo-randomly selected to vote on
+// every block. A block is only accepted when a majority of the tickets that
+// were selected to vote on its parent signed a vote. Miners therefore can't
+// build on a block that the stakeholders did not approve.
+//
+// The life of a ticket:
+//   - A ticket purchase is included in a block. The ticket commits to the
+//     PubKeyHash of the key that will sign its vote.
+//   - After TicketMaturity blocks the ticket enters the live ticket pool.
+//   - Once a block is mined, TicketsPerBlock tickets are selected from the
+//     pool using the block hash as seed. Since nobody can predict the hash of
+//     a block before it is mined, nobody can predict which tickets vote.
+//   - The next block carries the votes of the selected tickets. Selected
+//     tickets leave the pool whether they voted or not.
+//
+// There are no coins in these lessons yet so the ticket price is only
+// recorded and checked against the network minimum.
+
+// Ticket is a ticket purchase.
+type Ticket struct {
+	Price      uint64 // Price paid for the ticket
+	PubKeyHash []byte // Hash of the public key that signs the vote
+}
+
+// Hash returns the identifier of the ticket. A PubKeyHash can only be used by
+// a single ticket so, just like Decred wallets do, every ticket needs a fresh
+// voting key.
+func (t Ticket) Hash() []byte {
+	hash := sha256.Sum256(append(encodeUint64(t.Price), t.PubKeyHash...))
+	return hash[:]
+}
+
+// Vote is a signed vote of a selected ticket on a block.
+type Vote struct {
+	TicketHash []byte // Ticket that votes
+	BlockHash  []byte // Block that is voted on
+	Signature  []byte // Recoverable signature of the ticket voting key
+}
+
+// voteMessage returns the message that is signed by a vote.
+func voteMessage(ticketHash, blockHash []byte) []byte {
+	return append(append([]byte{}, ticketHash...), blockHash...)
+}
+
+// NewVote returns a vote of ticketHash on blockHash that is signed by key.
+func NewVote(key *PrivateKey, ticketHash, blockHash []byte) (*Vote, error) {
+	signature, err := key.SignMessage(voteMessage(ticketHash, blockHash))
+	if err != nil {
+		return nil, err
+	}
+	return &Vote{
+		TicketHash: ticketHash,
+		BlockHash:  blockHash,
+		Signature:  signature,
+	}, nil
+}
+
+// Hash returns the identifier of the vote.
+func (v Vote) Hash() []byte {
+	hash := sha256.Sum256(bytes.Join([][]byte{v.TicketHash, v.BlockHash,
+		v.Signature}, []byte{}))
+	return hash[:]
+}
+
+// Verify returns true if the vote was signed by the voting key of ticket.
+func (v Vote) Verify(ticket *Ticket) bool {
+	return VerifyMessage(&Address{PubKeyHash: ticket.PubKeyHash},
+		voteMessage(v.TicketHash, v.BlockHash), v.Signature)
+}
+
+// ticketEntry is a ticket in the pool along with the height of the block that
+// contains its purchase.
+type ticketEntry struct {
+	ticket *Ticket
+	height int
+}
+
+// TicketPool contains all tickets that have not been selected yet, including
+// immature tickets.
+type TicketPool struct {
+	maturity int                    // Blocks before a ticket is live
+	tickets  map[string]ticketEntry // Ticket hash to ticket
+}
+
+// NewTicketPool returns an empty ticket pool. Tickets become live maturity
+// blocks after their purchase.
+func NewTicketPool(maturity int) *TicketPool {
+	return &TicketPool{
+		maturity: maturity,
+		tickets:  make(map[string]ticketEntry),
+	}
+}
+
+// Ticket returns the ticket with hash or nil if it is not in the pool.
+func (p *TicketPool) Ticket(hash []byte) *Ticket {
+	e, ok := p.tickets[string(hash)]
+	if !ok {
+		return nil
+	}
+	return e.ticket
+}
+
+// Live returns the hashes of all tickets that are able to vote at height in
+// ascending order.
+func (p *TicketPool) Live(height int) [][]byte {
+	var live [][]byte
+	for hash, e := range p.tickets {
+		if e.height+p.maturity <= height {
+			live = append(live, []byte(hash))
+		}
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return bytes.Compare(live[i], live[j]) < 0
+	})
+	return live
+}
+
+// Len returns the number of tickets in the pool, including immature tickets.
+func (p *TicketPool) Len() int {
+	return len(p.tickets)
+}
+
+// SelectTickets pseudo-randomly selects count tickets out of live without
+// replacement. The selection is deterministic: the same seed always selects
+// the same tickets, however the outcome can't be predicted before the seed is
+// known. If live contains fewer than count tickets all are selected.
+func SelectTickets(live [][]byte, seed []byte, count int) [][]byte {
+	remaining := append([][]byte{}, live...)
+	selected := make([][]byte, 0, count)
+	for i := uint64(0); len(selected) < count && len(remaining) > 0; i++ {
+		// Hash seed and counter to pick the next ticket.
+		hash := sha256.Sum256(append(append([]byte{}, seed...),
+			encodeUint64(i)...))
+		n := binary.BigEndian.Uint64(hash[:8]) % uint64(len(remaining))
+		selected = append(selected, remaining[n])
+		remaining = append(remaining[:n], remaining[n+1:]...)
+	}
+	return selected
+}
+
+// majority returns the number of votes a block needs.
+func (p Params) majority() int {
+	return p.TicketsPerBlock/2 + 1
+}
+
+// Winners returns the tickets that were selected to vote on the last block.
+// Their votes must be included in the next block. It returns nil if the next
+// block is below the stake validation height.
+func (b Blockchain) Winners() [][]byte {
+	tip, height, err := b.Tip()
+	if err != nil || height+1 < b.params.StakeValidationHeight {
+		return nil
+	}
+	return SelectTickets(b.pool.Live(height), tip.Hash,
+		b.params.TicketsPerBlock)
+}
+
+// Pool returns the ticket pool.
+func (b Blockchain) Pool() *TicketPool {
+	return b.pool
+}
+
+// checkStake verifies the tickets and votes of blk at height.
+func (b *Blockchain) checkStake(height int, blk *Block) error {
+	purchased := make(map[string]struct{})
+	for _, t := range blk.Tickets {
+		if t.Price < b.params.TicketPrice {
+			return fmt.Errorf("ticket price %v below minimum %v",
+				t.Price, b.params.TicketPrice)
+		}
+		if len(t.PubKeyHash) != 20 {
+			return fmt.Errorf("invalid ticket PubKeyHash length: %v",
+				len(t.PubKeyHash))
+		}
+		hash := string(t.Hash())
+		if _, ok := purchased[hash]; ok || b.pool.Ticket(t.Hash()) != nil {
+			return fmt.Errorf("duplicate ticket %x", t.Hash())
+		}
+		purchased[hash] = struct{}{}
+	}
+
+	if height < b.params.StakeValidationHeight {
+		if len(blk.Votes) != 0 {
+			return fmt.Errorf("votes below stake validation height")
+		}
+		return nil
+	}
+
+	winners := make(map[string]struct{})
+	for _, w := range b.Winners() {
+		winners[string(w)] = struct{}{}
+	}
+	voted := make(map[string]struct{})
+	for _, v := range blk.Votes {
+		if !bytes.Equal(v.BlockHash, blk.PreviousBlockHash) {
+			return fmt.Errorf("vote on %x instead of previous block",
+				v.BlockHash)
+		}
+		if _, ok := winners[string(v.TicketHash)]; !ok {
+			return fmt.Errorf("ticket %x was not selected to vote",
+				v.TicketHash)
+		}
+		if _, ok := voted[string(v.TicketHash)]; ok {
+			return fmt.Errorf("duplicate vote of ticket %x",
+				v.TicketHash)
+		}
+		if !v.Verify(b.pool.Ticket(v.TicketHash)) {
+			return fmt.Errorf("invalid vote signature of ticket %x",
+				v.TicketHash)
+		}
+		voted[string(v.TicketHash)] = struct{}{}
+	}
+	if len(voted) < b.params.majority() {
+		return fmt.Errorf("block has %v votes, %v required", len(voted),
+			b.params.majority())
+	}
+	return nil
+}
+
+// connectStake updates the ticket pool with blk at height. The tickets that
+// were selected to vote on the parent leave the pool and the purchased tickets
+// are added.
+func (b *Blockchain) connectStake(height int, blk *Block) {
+	if height >= b.params.StakeValidationHeight {
+		for _, w := range b.Winners() {
+			delete(b.pool.tickets, string(w))
+		}
+	}
+	for _, t := range blk.Tickets {
+		b.pool.tickets[string(t.Hash())] = ticketEntry{
+			ticket: t,
+			height: height,
+		}
+	}
+}
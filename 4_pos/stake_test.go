@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stakeholder holds the voting keys of purchased tickets.
+type stakeholder struct {
+	keys    map[string]*PrivateKey // Ticket hash to voting key
+	entropy io.Reader              // Source of voting keys
+}
+
+// newStakeholder returns a stakeholder whose keys are created from a seeded
+// source.
+func newStakeholder() *stakeholder {
+	return &stakeholder{
+		keys:    make(map[string]*PrivateKey),
+		entropy: rand.New(rand.NewSource(1)),
+	}
+}
+
+// buyTickets returns count tickets, each with a fresh voting key.
+func (s *stakeholder) buyTickets(t *testing.T, count int) []*Ticket {
+	tickets := make([]*Ticket, 0, count)
+	for i := 0; i < count; i++ {
+		key, err := NewKey(s.entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ticket := &Ticket{
+			Price:      SimNetParams.TicketPrice,
+			PubKeyHash: NewPublicKey(key.Public()).Hash(),
+		}
+		s.keys[string(ticket.Hash())] = key
+		tickets = append(tickets, ticket)
+	}
+	return tickets
+}
+
+// vote returns the votes of the first count winners on the tip of b.
+func (s *stakeholder) vote(t *testing.T, b *Blockchain, count int) []*Vote {
+	tip, _, err := b.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var votes []*Vote
+	for _, w := range b.Winners()[:count] {
+		v, err := NewVote(s.keys[string(w)], w, tip.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes = append(votes, v)
+	}
+	return votes
+}
+
+// mine mines blk and tries to append it to b.
+func mine(t *testing.T, b *Blockchain, blk *Block) error {
+	if err := blk.Mine(b.Params().Difficulty); err != nil {
+		t.Fatal(err)
+	}
+	return b.Append(blk)
+}
+
+func TestSelectTickets(t *testing.T) {
+	var live [][]byte
+	for i := byte(0); i < 20; i++ {
+		live = append(live, []byte{i})
+	}
+	a := SelectTickets(live, []byte("seed"), 5)
+	b := SelectTickets(live, []byte("seed"), 5)
+	c := SelectTickets(live, []byte("other seed"), 5)
+	t.Logf("%x %x", a, c)
+	if len(a) != 5 {
+		t.Fatalf("selected %v tickets", len(a))
+	}
+	seen := make(map[string]struct{})
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			t.Fatalf("selection is not deterministic")
+		}
+		if _, ok := seen[string(a[i])]; ok {
+			t.Fatalf("ticket %x selected twice", a[i])
+		}
+		seen[string(a[i])] = struct{}{}
+	}
+	if bytes.Equal(bytes.Join(a, nil), bytes.Join(c, nil)) {
+		t.Fatalf("different seeds selected the same tickets")
+	}
+	if len(SelectTickets(live[:3], []byte("seed"), 5)) != 3 {
+		t.Fatalf("small pool not fully selected")
+	}
+}
+
+func TestProofOfStake(t *testing.T) {
+	params := &SimNetParams
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(params, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newStakeholder()
+
+	// Buy tickets until stake validation kicks in.
+	for b.Len() < params.StakeValidationHeight {
+		clock.Advance(time.Minute)
+		blk := b.PrepareBlock([]byte("tickets"))
+		blk.Tickets = s.buyTickets(t, 4)
+		if err := mine(t, b, blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Logf("pool: %v tickets, %v live", b.Pool().Len(),
+		len(b.Pool().Live(b.Len()-1)))
+
+	// Votes are not allowed before the stake validation height, so the
+	// winners of the tip are the first ones to vote.
+	winners := b.Winners()
+	if len(winners) != params.TicketsPerBlock {
+		t.Fatalf("%v winners", len(winners))
+	}
+
+	tip, _, _ := b.Tip()
+	otherKey, err := NewKey(s.entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := NewVote(otherKey, winners[0], tip.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loser []byte
+	for _, l := range b.Pool().Live(b.Len() - 1) {
+		if !bytes.Contains(bytes.Join(winners, nil), l) {
+			loser = l
+			break
+		}
+	}
+	loserVote, err := NewVote(s.keys[string(loser)], loser, tip.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongBlock, err := NewVote(s.keys[string(winners[0])], winners[0],
+		tip.PreviousBlockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		votes []*Vote
+		err   string
+	}{
+		{"no votes", nil, "0 votes, 3 required"},
+		{"minority", s.vote(t, b, 2), "2 votes, 3 required"},
+		{
+			"duplicate",
+			append(s.vote(t, b, 2), s.vote(t, b, 1)...),
+			"duplicate vote",
+		},
+		{
+			"wrong key",
+			append(s.vote(t, b, 3)[1:], bad),
+			"invalid vote signature",
+		},
+		{
+			"not selected",
+			append(s.vote(t, b, 2), loserVote),
+			"was not selected",
+		},
+		{
+			"wrong block",
+			append(s.vote(t, b, 3)[1:], wrongBlock),
+			"instead of previous block",
+		},
+	}
+	for _, test := range tests {
+		blk := b.PrepareBlock([]byte(test.name))
+		blk.Votes = test.votes
+		err := mine(t, b, blk)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+
+	// Keep voting with a bare majority until the pool runs dry. Every
+	// block removes the selected tickets from the pool.
+	for len(b.Winners()) >= params.majority() {
+		poolSize := b.Pool().Len()
+		clock.Advance(time.Minute)
+		blk := b.PrepareBlock([]byte("votes"))
+		blk.Votes = s.vote(t, b, params.majority())
+		if err := mine(t, b, blk); err != nil {
+			t.Fatal(err)
+		}
+		if b.Pool().Len() != poolSize-params.TicketsPerBlock {
+			t.Fatalf("selected tickets not removed from pool")
+		}
+	}
+
+	// Without enough live tickets the chain can't make progress.
+	blk := b.PrepareBlock([]byte("halted"))
+	blk.Votes = s.vote(t, b, len(b.Winners()))
+	if err := mine(t, b, blk); err == nil {
+		t.Fatalf("block appended without majority")
+	}
+	t.Logf("chain halted at height %v", b.Len()-1)
+}
+
+func TestTicketRules(t *testing.T) {
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newStakeholder()
+	tickets := s.buyTickets(t, 2)
+
+	cheap := *tickets[0]
+	cheap.Price--
+	tests := []struct {
+		name    string
+		tickets []*Ticket
+		votes   []*Vote
+		err     string
+	}{
+		{"cheap", []*Ticket{&cheap}, nil, "below minimum"},
+		{
+			"duplicate",
+			[]*Ticket{tickets[0], tickets[0]},
+			nil,
+			"duplicate ticket",
+		},
+		{
+			"early vote",
+			nil,
+			[]*Vote{{TicketHash: tickets[0].Hash()}},
+			"below stake validation height",
+		},
+	}
+	for _, test := range tests {
+		blk := b.PrepareBlock([]byte(test.name))
+		blk.Tickets = test.tickets
+		blk.Votes = test.votes
+		err := mine(t, b, blk)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+	}
+
+	// A ticket can only be purchased once.
+	blk := b.PrepareBlock(nil)
+	blk.Tickets = tickets
+	if err := mine(t, b, blk); err != nil {
+		t.Fatal(err)
+	}
+	blk = b.PrepareBlock(nil)
+	blk.Tickets = tickets[1:]
+	if err := mine(t, b, blk); err == nil {
+		t.Fatalf("ticket purchased twice")
+	}
+
+	// Changing a vote or ticket after mining invalidates the block.
+	blk.Tickets = nil
+	if blk.Verify() {
+		t.Fatalf("stake root not committed to")
+	}
+}
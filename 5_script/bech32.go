@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bech32 is a human friendly address format that is described in BIP173. It
+// consists of a human-readable part (HRP), the separator '1' and a data part
+// that is encoded in 5 bit groups and terminated by a 6 character checksum.
+// The checksum is a BCH code that guarantees detection of any error affecting
+// at most 4 characters. Bech32m (BIP350) is identical except for the constant
+// that is mixed into the checksum.
+
+const (
+	// charset is the bech32 alphabet. Each character encodes 5 bits.
+	charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Separator = '1' // Separates the HRP from the data part
+	bech32MaxLength = 90  // Maximum length of an encoded string
+	checksumLength  = 6   // Length of the checksum in characters
+
+	bech32Const  = 1          // Checksum constant for Bech32
+	bech32mConst = 0x2bc830a3 // Checksum constant for Bech32m
+)
+
+// Bech32Variant selects the checksum constant of a bech32 string.
+type Bech32Variant int
+
+const (
+	Bech32  Bech32Variant = iota // BIP173 checksum
+	Bech32m                      // BIP350 checksum
+)
+
+// String returns the human readable name of the variant.
+func (v Bech32Variant) String() string {
+	switch v {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	}
+	return fmt.Sprintf("unknown variant %d", int(v))
+}
+
+// constant returns the checksum constant of the variant.
+func (v Bech32Variant) constant() uint32 {
+	if v == Bech32m {
+		return bech32mConst
+	}
+	return bech32Const
+}
+
+// polymod calculates the BCH checksum over 5 bit values.
+func polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd,
+		0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// hrpExpand expands the HRP into values for checksum computation.
+func hrpExpand(hrp string) []byte {
+	v := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		v = append(v, hrp[i]>>5)
+	}
+	v = append(v, 0)
+	for i := 0; i < len(hrp); i++ {
+		v = append(v, hrp[i]&31)
+	}
+	return v
+}
+
+// bech32Checksum returns the checksum of hrp and the 5 bit data values.
+func bech32Checksum(hrp string, data []byte, variant Bech32Variant) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, make([]byte, checksumLength)...)
+	mod := polymod(values) ^ variant.constant()
+	chk := make([]byte, checksumLength)
+	for i := range chk {
+		chk[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return chk
+}
+
+// Bech32Encode encodes hrp and the 5 bit data values into a lowercase bech32
+// string using the checksum of variant.
+func Bech32Encode(hrp string, data []byte, variant Bech32Variant) (string,
+	error) {
+
+	if len(hrp) < 1 {
+		return "", fmt.Errorf("empty human-readable part")
+	}
+	if len(hrp)+len(data)+1+checksumLength > bech32MaxLength {
+		return "", fmt.Errorf("encoded length exceeds %v",
+			bech32MaxLength)
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("invalid human-readable part "+
+				"character at position %v", i)
+		}
+	}
+	for i, v := range data {
+		if v > 31 {
+			return "", fmt.Errorf("invalid data value at position "+
+				"%v: %v", i, v)
+		}
+	}
+	hrp = strings.ToLower(hrp)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte(bech32Separator)
+	for _, v := range data {
+		sb.WriteByte(charset[v])
+	}
+	for _, v := range bech32Checksum(hrp, data, variant) {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String(), nil
+}
+
+// Bech32Decode decodes a bech32 or bech32m string. It returns the lowercase
+// HRP, the 5 bit data values without the checksum and the variant that
+// matched the checksum.
+func Bech32Decode(s string) (string, []byte, Bech32Variant, error) {
+	if len(s) > bech32MaxLength {
+		return "", nil, 0, fmt.Errorf("length exceeds %v",
+			bech32MaxLength)
+	}
+	lower, upper := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			return "", nil, 0, fmt.Errorf("invalid character at "+
+				"position %v", i)
+		}
+		lower = lower || (c >= 'a' && c <= 'z')
+		upper = upper || (c >= 'A' && c <= 'Z')
+	}
+	if lower && upper {
+		return "", nil, 0, fmt.Errorf("mixed case")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, bech32Separator)
+	if sep < 0 {
+		return "", nil, 0, fmt.Errorf("missing separator")
+	}
+	if sep == 0 {
+		return "", nil, 0, fmt.Errorf("empty human-readable part")
+	}
+	if len(s)-sep-1 < checksumLength {
+		return "", nil, 0, fmt.Errorf("checksum too short")
+	}
+
+	hrp := s[:sep]
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(charset, s[i])
+		if v < 0 {
+			return "", nil, 0, fmt.Errorf("invalid data character "+
+				"at position %v", i)
+		}
+		data = append(data, byte(v))
+	}
+
+	var variant Bech32Variant
+	switch polymod(append(hrpExpand(hrp), data...)) {
+	case bech32Const:
+		variant = Bech32
+	case bech32mConst:
+		variant = Bech32m
+	default:
+		return "", nil, 0, fmt.Errorf("invalid checksum")
+	}
+	return hrp, data[:len(data)-checksumLength], variant, nil
+}
+
+// convertBits regroups data from groups of fromBits bits into groups of
+// toBits bits. When pad is set incomplete trailing groups are zero padded,
+// otherwise they must be zero and are dropped.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte,
+	error) {
+
+	var (
+		acc  uint32
+		bits uint
+		out  []byte
+	)
+	maxv := uint32(1)<<toBits - 1
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid value: %v", v)
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return out, nil
+}
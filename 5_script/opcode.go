@@ -0,0 +1,103 @@
+package main
+
+// The opcodes use the same values and names as Bitcoin so that scripts can be
+// compared with Bitcoin documentation.
+const (
+	OP_0                   = 0x00 // Push an empty array
+	OP_DATA_1              = 0x01 // Push the next byte
+	OP_DATA_75             = 0x4b // Push the next 75 bytes
+	OP_PUSHDATA1           = 0x4c // Push the next uint8 length bytes
+	OP_PUSHDATA2           = 0x4d // Push the next uint16 length bytes
+	OP_1NEGATE             = 0x4f // Push -1
+	OP_1                   = 0x51 // Push 1, OP_2 through OP_16 follow
+	OP_16                  = 0x60 // Push 16
+	OP_NOP                 = 0x61 // Do nothing
+	OP_IF                  = 0x63 // Execute if top is true
+	OP_NOTIF               = 0x64 // Execute if top is false
+	OP_ELSE                = 0x67 // Execute if the previous branch did not
+	OP_ENDIF               = 0x68 // End of conditional
+	OP_VERIFY              = 0x69 // Fail unless top is true
+	OP_RETURN              = 0x6a // Fail, marks unspendable outputs
+	OP_DROP                = 0x75 // Remove top
+	OP_DUP                 = 0x76 // Duplicate top
+	OP_SWAP                = 0x7c // Swap the top two items
+	OP_SIZE                = 0x82 // Push the size of top
+	OP_EQUAL               = 0x87 // Push true if the top two items are equal
+	OP_EQUALVERIFY         = 0x88 // OP_EQUAL followed by OP_VERIFY
+	OP_SHA256              = 0xa8 // Replace top with sha256(top)
+	OP_HASH160             = 0xa9 // Replace top with ripemd160(sha256(top))
+	OP_CHECKSIG            = 0xac // Verify a signature of the transaction
+	OP_CHECKSIGVERIFY      = 0xad // OP_CHECKSIG followed by OP_VERIFY
+	OP_CHECKMULTISIG       = 0xae // Verify m of n signatures
+	OP_CHECKMULTISIGVERIFY = 0xaf // OP_CHECKMULTISIG followed by OP_VERIFY
+	OP_CHECKLOCKTIMEVERIFY = 0xb1 // Fail if the lock time is too early
+	OP_CHECKSEQUENCEVERIFY = 0xb2 // Fail if the input sequence is too low
+)
+
+// opcodeNames maps all named opcodes to their names. Data pushes are not
+// named.
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_1:                   "OP_1",
+	OP_1 + 1:               "OP_2",
+	OP_1 + 2:               "OP_3",
+	OP_1 + 3:               "OP_4",
+	OP_1 + 4:               "OP_5",
+	OP_1 + 5:               "OP_6",
+	OP_1 + 6:               "OP_7",
+	OP_1 + 7:               "OP_8",
+	OP_1 + 8:               "OP_9",
+	OP_1 + 9:               "OP_10",
+	OP_1 + 10:              "OP_11",
+	OP_1 + 11:              "OP_12",
+	OP_1 + 12:              "OP_13",
+	OP_1 + 13:              "OP_14",
+	OP_1 + 14:              "OP_15",
+	OP_16:                  "OP_16",
+	OP_NOP:                 "OP_NOP",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+// opcodeValues maps opcode names to their values.
+var opcodeValues = func() map[string]byte {
+	m := make(map[string]byte, len(opcodeNames))
+	for op, name := range opcodeNames {
+		m[name] = op
+	}
+	return m
+}()
+
+// isSmallInt returns true if op pushes a number between 0 and 16.
+func isSmallInt(op byte) bool {
+	return op == OP_0 || (op >= OP_1 && op <= OP_16)
+}
+
+// smallInt returns the number that a small integer opcode pushes.
+func smallInt(op byte) int {
+	if op == OP_0 {
+		return 0
+	}
+	return int(op-OP_1) + 1
+}
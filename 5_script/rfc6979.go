@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+)
+
+// ECDSA needs a secret nonce k for every signature. A nonce that is reused or
+// even slightly predictable leaks the private key, so instead of drawing it
+// from an entropy source it is derived from the private key and the signed
+// hash as specified by RFC 6979. Signatures are therefore reproducible: the
+// same key always produces the same signature for the same hash.
+
+// hashToInt converts hash to an integer the way ecdsa.Verify does: a hash
+// that is longer than the curve order is truncated to its leftmost bits.
+func hashToInt(hash []byte, c elliptic.Curve) *big.Int {
+	orderBits := c.Params().N.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+	x := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		x.Rsh(x, uint(excess))
+	}
+	return x
+}
+
+// nonceRFC6979 returns a function that returns the deterministic nonces of
+// RFC 6979 section 3.2 for private key d and hash, using HMAC-SHA256. The
+// function is called again in the unlikely event that a nonce results in an
+// invalid signature.
+func nonceRFC6979(c elliptic.Curve, d *big.Int, hash []byte) func() *big.Int {
+	n := c.Params().N
+	size := (n.BitLen() + 7) / 8
+	x := d.FillBytes(make([]byte, size))
+	h := new(big.Int).Mod(hashToInt(hash, c), n).FillBytes(make([]byte,
+		size))
+
+	mac := func(k []byte, blobs ...[]byte) []byte {
+		m := hmac.New(sha256.New, k)
+		for _, b := range blobs {
+			m.Write(b)
+		}
+		return m.Sum(nil)
+	}
+	v := make([]byte, sha256.Size)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, sha256.Size)
+	k = mac(k, v, []byte{0x00}, x, h)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h)
+	v = mac(k, v)
+
+	first := true
+	return func() *big.Int {
+		for {
+			if !first {
+				k = mac(k, v, []byte{0x00})
+				v = mac(k, v)
+			}
+			first = false
+
+			var t []byte
+			for len(t) < size {
+				v = mac(k, v)
+				t = append(t, v...)
+			}
+			nonce := hashToInt(t[:size], c)
+			if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+				return nonce
+			}
+		}
+	}
+}
+
+// signRFC6979 returns the ECDSA signature (r, s) of hash with a deterministic
+// nonce.
+func signRFC6979(p *ecdsa.PrivateKey, hash []byte) (*big.Int, *big.Int) {
+	c := p.Curve
+	n := c.Params().N
+	e := hashToInt(hash, c)
+	nonce := nonceRFC6979(c, p.D, hash)
+	for {
+		k := nonce()
+		r, _ := c.ScalarBaseMult(k.FillBytes(make([]byte,
+			(n.BitLen()+7)/8)))
+		r.Mod(r, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// s = k^-1 * (e + r*d) mod n
+		s := new(big.Int).Mul(r, p.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+		return r, s
+	}
+}
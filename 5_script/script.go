@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// A script is a sequence of opcodes. Opcodes up to OP_PUSHDATA2 push data on
+// the stack, all others operate on the stack.
+
+const (
+	maxScriptSize         = 10000 // Maximum size of a script
+	maxScriptElementSize  = 520   // Maximum size of a pushed element
+	maxStackSize          = 1000  // Maximum number of stack items
+	maxPubKeysPerMultiSig = 20    // Maximum n of OP_CHECKMULTISIG
+	maxScriptNumLen       = 4     // Maximum size of a number operand
+	maxLockTimeNumLen     = 5     // Maximum size of a time lock operand
+)
+
+// parsedOpcode is an opcode along with the data it pushes.
+type parsedOpcode struct {
+	opcode byte   // Opcode
+	data   []byte // Pushed data, only set for data pushes
+}
+
+// isPush returns true if the opcode only pushes data.
+func (p parsedOpcode) isPush() bool {
+	return p.opcode <= OP_PUSHDATA2 || p.opcode == OP_1NEGATE ||
+		(p.opcode >= OP_1 && p.opcode <= OP_16)
+}
+
+// String returns the opcode name or the pushed data in hex.
+func (p parsedOpcode) String() string {
+	if p.opcode > OP_0 && p.opcode <= OP_PUSHDATA2 {
+		return hex.EncodeToString(p.data)
+	}
+	if name, ok := opcodeNames[p.opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("OP_UNKNOWN%d", p.opcode)
+}
+
+// parseScript splits script into opcodes.
+func parseScript(script []byte) ([]parsedOpcode, error) {
+	if len(script) > maxScriptSize {
+		return nil, fmt.Errorf("script too long: %v", len(script))
+	}
+	var ops []parsedOpcode
+	for i := 0; i < len(script); {
+		op := script[i]
+		i++
+
+		var l int
+		switch {
+		case op >= OP_DATA_1 && op <= OP_DATA_75:
+			l = int(op)
+		case op == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("truncated OP_PUSHDATA1")
+			}
+			l = int(script[i])
+			i++
+		case op == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("truncated OP_PUSHDATA2")
+			}
+			l = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		default:
+			ops = append(ops, parsedOpcode{opcode: op})
+			continue
+		}
+		if i+l > len(script) {
+			return nil, fmt.Errorf("push of %v bytes exceeds script", l)
+		}
+		ops = append(ops, parsedOpcode{opcode: op, data: script[i : i+l]})
+		i += l
+	}
+	return ops, nil
+}
+
+// Disassemble returns the human readable representation of script. Named
+// opcodes are printed by name and pushed data in hex.
+func Disassemble(script []byte) (string, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return "", err
+	}
+	s := make([]string, 0, len(ops))
+	for _, op := range ops {
+		s = append(s, op.String())
+	}
+	return strings.Join(s, " "), nil
+}
+
+// Assemble returns the script that is described by s. Every whitespace
+// separated token is either an opcode name or hex encoded data to push.
+func Assemble(s string) ([]byte, error) {
+	b := NewScriptBuilder()
+	for _, token := range strings.Fields(s) {
+		if op, ok := opcodeValues[token]; ok {
+			b.AddOp(op)
+			continue
+		}
+		if strings.HasPrefix(token, "OP_") {
+			return nil, fmt.Errorf("unknown opcode: %v", token)
+		}
+		data, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %q: %v", token, err)
+		}
+		b.AddData(data)
+	}
+	return b.Script()
+}
+
+// ScriptBuilder builds scripts with canonical data pushes.
+type ScriptBuilder struct {
+	script []byte
+	err    error
+}
+
+// NewScriptBuilder returns an empty script builder.
+func NewScriptBuilder() *ScriptBuilder {
+	return &ScriptBuilder{}
+}
+
+// AddOp appends op.
+func (b *ScriptBuilder) AddOp(op byte) *ScriptBuilder {
+	b.script = append(b.script, op)
+	return b
+}
+
+// AddData appends the smallest push of data.
+func (b *ScriptBuilder) AddData(data []byte) *ScriptBuilder {
+	l := len(data)
+	switch {
+	case l > maxScriptElementSize:
+		b.err = fmt.Errorf("push of %v bytes too large", l)
+	case l == 0:
+		b.script = append(b.script, OP_0)
+	case l == 1 && data[0] >= 1 && data[0] <= 16:
+		b.script = append(b.script, OP_1+data[0]-1)
+	case l <= OP_DATA_75:
+		b.script = append(b.script, byte(l))
+		b.script = append(b.script, data...)
+	case l <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(l))
+		b.script = append(b.script, data...)
+	default:
+		b.script = append(b.script, OP_PUSHDATA2, byte(l), byte(l>>8))
+		b.script = append(b.script, data...)
+	}
+	return b
+}
+
+// AddInt64 appends a push of n as a script number.
+func (b *ScriptBuilder) AddInt64(n int64) *ScriptBuilder {
+	switch {
+	case n == -1:
+		return b.AddOp(OP_1NEGATE)
+	case n == 0:
+		return b.AddOp(OP_0)
+	case n >= 1 && n <= 16:
+		return b.AddOp(OP_1 + byte(n) - 1)
+	}
+	return b.AddData(scriptNum(n))
+}
+
+// Script returns the script or the first error that occurred while building
+// it.
+func (b *ScriptBuilder) Script() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.script) > maxScriptSize {
+		return nil, fmt.Errorf("script too long: %v", len(b.script))
+	}
+	return b.script, nil
+}
+
+// scriptNum returns n as a script number: little endian with the sign in the
+// most significant bit. Zero is the empty array.
+func scriptNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	var b []byte
+	for n > 0 {
+		b = append(b, byte(n&0xff))
+		n >>= 8
+	}
+	// Add a byte for the sign if the top bit is in use.
+	if b[len(b)-1]&0x80 != 0 {
+		if negative {
+			b = append(b, 0x80)
+		} else {
+			b = append(b, 0x00)
+		}
+	} else if negative {
+		b[len(b)-1] |= 0x80
+	}
+	return b
+}
+
+// makeScriptNum decodes a script number of at most maxLen bytes. Numbers
+// must be minimally encoded.
+func makeScriptNum(b []byte, maxLen int) (int64, error) {
+	if len(b) > maxLen {
+		return 0, fmt.Errorf("number too long: %v bytes", len(b))
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if b[len(b)-1]&0x7f == 0 &&
+		(len(b) == 1 || b[len(b)-2]&0x80 == 0) {
+		return 0, fmt.Errorf("number not minimally encoded: %x", b)
+	}
+	var n int64
+	for i, c := range b {
+		n |= int64(c) << uint(8*i)
+	}
+	if b[len(b)-1]&0x80 != 0 {
+		n &^= int64(0x80) << uint(8*(len(b)-1))
+		return -n, nil
+	}
+	return n, nil
+}
+
+// PayToPubKeyHashScript returns a script that pays to the owner of the
+// private key that hashes to pubKeyHash:
+//
+//	OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+func PayToPubKeyHashScript(pubKeyHash []byte) ([]byte, error) {
+	return NewScriptBuilder().AddOp(OP_DUP).AddOp(OP_HASH160).
+		AddData(pubKeyHash).AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// PayToAddrScript returns a pay-to-pubkey-hash script for address a.
+func PayToAddrScript(a *Address) ([]byte, error) {
+	return PayToPubKeyHashScript(a.PubKeyHash)
+}
+
+// MultiSigScript returns a script that requires m signatures of the private
+// keys that belong to pubKeys:
+//
+//	<m> <pubkey>... <n> OP_CHECKMULTISIG
+func MultiSigScript(m int, pubKeys [][]byte) ([]byte, error) {
+	if m < 1 || m > len(pubKeys) || len(pubKeys) > maxPubKeysPerMultiSig {
+		return nil, fmt.Errorf("invalid multisig %v of %v", m,
+			len(pubKeys))
+	}
+	b := NewScriptBuilder().AddInt64(int64(m))
+	for _, pk := range pubKeys {
+		b.AddData(pk)
+	}
+	return b.AddInt64(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// SignatureScript returns the signature script that spends the
+// pay-to-pubkey-hash output pkScript with input idx of tx:
+//
+//	<signature> <pubkey>
+func SignatureScript(tx *Tx, idx int, pkScript []byte,
+	key *PrivateKey) ([]byte, error) {
+
+	sig, err := key.Sign(tx.SignatureHash(idx, pkScript))
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptBuilder().AddData(sig).AddData(key.Public()).Script()
+}
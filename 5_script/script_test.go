@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// newKey returns a new private key from entropy and fails the test on error.
+func newKey(t *testing.T, entropy io.Reader) *PrivateKey {
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// spendTx returns a transaction with a single input that spends a previous
+// output and a single output.
+func spendTx(lockTime, sequence uint32) *Tx {
+	return &Tx{
+		Inputs: []*TxIn{{
+			PreviousOutPoint: OutPoint{
+				Hash:  bytes.Repeat([]byte{0xaa}, 32),
+				Index: 1,
+			},
+			Sequence: sequence,
+		}},
+		Outputs:  []*TxOut{{Value: 5000, PkScript: []byte{OP_1}}},
+		LockTime: lockTime,
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []string{
+		"OP_DUP OP_HASH160 00112233445566778899aabbccddeeff00112233 " +
+			"OP_EQUALVERIFY OP_CHECKSIG",
+		"OP_0 OP_1NEGATE OP_1 OP_16 OP_IF OP_ELSE OP_ENDIF",
+		"OP_2 aa bb OP_2 OP_CHECKMULTISIG",
+		strings.Repeat("ab", 76) + " " + strings.Repeat("cd", 256),
+		"80969800 OP_CHECKLOCKTIMEVERIFY OP_DROP",
+	}
+	for _, test := range tests {
+		script, err := Assemble(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := Disassemble(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != test {
+			t.Fatalf("round trip: got %v want %v", s, test)
+		}
+		t.Logf("%x", script)
+	}
+
+	// Small numbers are pushed with their own opcodes.
+	script, err := Assemble("01 10 11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(script, []byte{OP_1, OP_16, OP_DATA_1, 0x11}) {
+		t.Fatalf("non canonical push: %x", script)
+	}
+
+	for _, s := range []string{"OP_FOO", "zz", "abc"} {
+		if _, err := Assemble(s); err == nil {
+			t.Fatalf("%v assembled", s)
+		}
+	}
+	truncated := [][]byte{{OP_DATA_1}, {OP_PUSHDATA1}, {OP_PUSHDATA2, 1}}
+	for _, s := range truncated {
+		if _, err := Disassemble(s); err == nil {
+			t.Fatalf("%x disassembled", s)
+		}
+	}
+}
+
+func TestScriptNum(t *testing.T) {
+	tests := []struct {
+		n       int64
+		encoded string
+	}{
+		{0, ""},
+		{1, "01"},
+		{-1, "81"},
+		{127, "7f"},
+		{128, "8000"},
+		{-128, "8080"},
+		{255, "ff00"},
+		{256, "0001"},
+		{-32768, "008080"},
+		{2147483647, "ffffff7f"},
+	}
+	for _, test := range tests {
+		b := scriptNum(test.n)
+		if hex.EncodeToString(b) != test.encoded {
+			t.Fatalf("%v: got %x want %v", test.n, b, test.encoded)
+		}
+		n, err := makeScriptNum(b, maxScriptNumLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != test.n {
+			t.Fatalf("%x: got %v want %v", b, n, test.n)
+		}
+	}
+
+	for _, s := range []string{"00", "80", "0100", "ff0000", "0000000001"} {
+		b, _ := hex.DecodeString(s)
+		if _, err := makeScriptNum(b, maxScriptNumLen); err == nil {
+			t.Fatalf("%v decoded", s)
+		}
+	}
+}
+
+func TestPayToPubKeyHash(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	key := newKey(t, entropy)
+	addr := NewPublicKey(key.Public()).Address(&SimNetParams)
+	pkScript, err := PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asm, _ := Disassemble(pkScript)
+	t.Logf("pkScript: %v", asm)
+
+	tx := spendTx(0, MaxSequence)
+	tx.Inputs[0].SignatureScript, err = SignatureScript(tx, 0, pkScript,
+		key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEngine(tx, 0, pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace bytes.Buffer
+	e.Trace = &trace
+	if err := e.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("trace:\n%v", trace.String())
+	if lines := strings.Count(trace.String(), "\n"); lines != 7 {
+		t.Fatalf("traced %v opcodes, want 7", lines)
+	}
+	golden(t, "p2pkh", trace.Bytes())
+
+	// The signature commits to the outputs.
+	tx.Outputs[0].Value++
+	if err := VerifyInput(tx, 0, pkScript); err != ErrScriptFalse {
+		t.Fatalf("modified transaction: %v", err)
+	}
+	tx.Outputs[0].Value--
+
+	// Someone else's key doesn't hash to the public key hash.
+	other := newKey(t, entropy)
+	tx.Inputs[0].SignatureScript, err = SignatureScript(tx, 0, pkScript,
+		other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyInput(tx, 0, pkScript)
+	if err == nil || !strings.Contains(err.Error(), "verify failed") {
+		t.Fatalf("wrong key: %v", err)
+	}
+	t.Logf("wrong key: %v", err)
+
+	// Signature scripts may only push data.
+	tx.Inputs[0].SignatureScript = []byte{OP_1, OP_DUP}
+	if err := VerifyInput(tx, 0, pkScript); err == nil {
+		t.Fatalf("non push signature script accepted")
+	}
+}
+
+func TestMultiSig(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	keys := []*PrivateKey{newKey(t, entropy), newKey(t, entropy),
+		newKey(t, entropy)}
+	pubKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		pubKeys = append(pubKeys, key.Public())
+	}
+	pkScript, err := MultiSigScript(2, pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		signers []int
+		valid   bool
+	}{
+		{"first two", []int{0, 1}, true},
+		{"first and last", []int{0, 2}, true},
+		{"last two", []int{1, 2}, true},
+		{"out of order", []int{2, 0}, false},
+		{"same key twice", []int{1, 1}, false},
+	}
+	for _, test := range tests {
+		tx := spendTx(0, MaxSequence)
+		hash := tx.SignatureHash(0, pkScript)
+		b := NewScriptBuilder()
+		for _, i := range test.signers {
+			sig, err := keys[i].Sign(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.AddData(sig)
+		}
+		tx.Inputs[0].SignatureScript, err = b.Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err := VerifyInput(tx, 0, pkScript)
+		if test.valid != (err == nil) {
+			t.Fatalf("%v: unexpected result: %v", test.name, err)
+		}
+	}
+
+	// Too few signatures underflow the stack.
+	tx := spendTx(0, MaxSequence)
+	sig, err := keys[0].Sign(tx.SignatureHash(0, pkScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Inputs[0].SignatureScript, err = NewScriptBuilder().AddData(sig).
+		Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyInput(tx, 0, pkScript); err == nil {
+		t.Fatalf("single signature accepted")
+	}
+
+	if _, err := MultiSigScript(3, pubKeys[:2]); err == nil {
+		t.Fatalf("3 of 2 multisig created")
+	}
+}
+
+func TestTimeLocks(t *testing.T) {
+	const (
+		height    = 1000
+		timestamp = 1600000000
+	)
+	tests := []struct {
+		name     string
+		script   string
+		lockTime uint32
+		sequence uint32
+		err      string
+	}{
+		{"height", "e803 OP_CHECKLOCKTIMEVERIFY", height, 0, ""},
+		{"later height", "e803 OP_CHECKLOCKTIMEVERIFY", height + 1, 0, ""},
+		{
+			"early height",
+			"e803 OP_CHECKLOCKTIMEVERIFY",
+			height - 1,
+			0,
+			"not reached",
+		},
+		{"time", "00105e5f OP_CHECKLOCKTIMEVERIFY", timestamp, 0, ""},
+		{
+			"time vs height",
+			"00105e5f OP_CHECKLOCKTIMEVERIFY",
+			height,
+			0,
+			"type mismatch",
+		},
+		{
+			"final sequence",
+			"e803 OP_CHECKLOCKTIMEVERIFY",
+			height,
+			MaxSequence,
+			"disables lock time",
+		},
+		{
+			"negative",
+			"OP_1NEGATE OP_CHECKLOCKTIMEVERIFY",
+			height,
+			0,
+			"negative lock time",
+		},
+		{"sequence", "OP_10 OP_CHECKSEQUENCEVERIFY", 0, 10, ""},
+		{
+			"early sequence",
+			"OP_10 OP_CHECKSEQUENCEVERIFY",
+			0,
+			9,
+			"not reached",
+		},
+		{
+			"disabled sequence",
+			"OP_10 OP_CHECKSEQUENCEVERIFY",
+			0,
+			MaxSequence,
+			"disables relative lock time",
+		},
+		{
+			"if else",
+			"OP_0 OP_IF e803 OP_CHECKLOCKTIMEVERIFY OP_ELSE OP_1 " +
+				"OP_ENDIF",
+			0,
+			MaxSequence,
+			"",
+		},
+		{"unbalanced", "OP_1 OP_IF OP_1", 0, 0, "unbalanced conditional"},
+		{"return", "OP_1 OP_RETURN", 0, 0, "OP_RETURN executed"},
+	}
+	for _, test := range tests {
+		pkScript, err := Assemble(test.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := spendTx(test.lockTime, test.sequence)
+		err = VerifyInput(tx, 0, pkScript)
+		switch {
+		case test.err == "" && err != nil:
+			t.Fatalf("%v: %v", test.name, err)
+		case test.err != "" &&
+			(err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+}
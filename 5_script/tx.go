@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// A transaction moves value from previous transaction outputs to new outputs.
+// Every output is locked by a public key script (PkScript) and every input
+// unlocks the output it spends with a signature script (SignatureScript). The
+// input is valid when executing the signature script followed by the public
+// key script leaves true on the stack.
+
+const (
+	// MaxSequence is the sequence of an input that opts out of time locks.
+	MaxSequence = 0xffffffff
+
+	// LockTimeThreshold is the smallest lock time that is interpreted as
+	// a unix timestamp. Smaller lock times are block heights.
+	LockTimeThreshold = 500000000
+)
+
+// OutPoint identifies a transaction output.
+type OutPoint struct {
+	Hash  []byte // Hash of the transaction that contains the output
+	Index uint32 // Index of the output
+}
+
+// TxIn is a transaction input.
+type TxIn struct {
+	PreviousOutPoint OutPoint // Output that is spent
+	SignatureScript  []byte   // Script that unlocks the output
+	Sequence         uint32   // Relative time lock, MaxSequence disables
+}
+
+// TxOut is a transaction output.
+type TxOut struct {
+	Value    uint64 // Amount in atoms
+	PkScript []byte // Script that locks the output
+}
+
+// Tx is a transaction.
+type Tx struct {
+	Inputs   []*TxIn  // Spent outputs
+	Outputs  []*TxOut // Created outputs
+	LockTime uint32   // Height or timestamp before which tx is invalid
+}
+
+// writeVarBytes writes the varint length of b followed by b.
+func writeVarBytes(w *bytes.Buffer, b []byte) {
+	var l [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(l[:], uint64(len(b)))
+	w.Write(l[:n])
+	w.Write(b)
+}
+
+// writeUint writes x as a big endian value of size bytes.
+func writeUint(w *bytes.Buffer, x uint64, size int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	w.Write(b[8-size:])
+}
+
+// serialize returns the binary representation of the transaction. When
+// signing, the signature script of input idx is replaced with subScript and
+// all other signature scripts are omitted because a signature can't sign
+// itself.
+func (tx *Tx) serialize(signing bool, idx int, subScript []byte) []byte {
+	var b bytes.Buffer
+	writeUint(&b, uint64(len(tx.Inputs)), 4)
+	for i, in := range tx.Inputs {
+		writeVarBytes(&b, in.PreviousOutPoint.Hash)
+		writeUint(&b, uint64(in.PreviousOutPoint.Index), 4)
+		switch {
+		case !signing:
+			writeVarBytes(&b, in.SignatureScript)
+		case i == idx:
+			writeVarBytes(&b, subScript)
+		default:
+			writeVarBytes(&b, nil)
+		}
+		writeUint(&b, uint64(in.Sequence), 4)
+	}
+	writeUint(&b, uint64(len(tx.Outputs)), 4)
+	for _, out := range tx.Outputs {
+		writeUint(&b, out.Value, 8)
+		writeVarBytes(&b, out.PkScript)
+	}
+	writeUint(&b, uint64(tx.LockTime), 4)
+	return b.Bytes()
+}
+
+// doubleSHA256 returns sha256(sha256(blob)).
+func doubleSHA256(blob []byte) []byte {
+	h0 := sha256.Sum256(blob)
+	h1 := sha256.Sum256(h0[:])
+	return h1[:]
+}
+
+// Hash returns the transaction hash that identifies it.
+func (tx *Tx) Hash() []byte {
+	return doubleSHA256(tx.serialize(false, 0, nil))
+}
+
+// SignatureHash returns the hash that is signed in order to spend input idx.
+// The hash commits to all inputs, all outputs and the lock time as well as to
+// the public key script of the output that input idx spends.
+func (tx *Tx) SignatureHash(idx int, pkScript []byte) []byte {
+	return doubleSHA256(tx.serialize(true, idx, pkScript))
+}
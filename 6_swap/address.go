@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/golangcrypto/ripemd160"
+)
+
+// coordinateSize is the size of a P256 coordinate or signature scalar.
+const coordinateSize = 32
+
+// joinCoordinates returns a and b as fixed size big endian values so that the
+// result can be split in half again, even when a has leading zeros.
+func joinCoordinates(a, b *big.Int) []byte {
+	blob := make([]byte, 2*coordinateSize)
+	a.FillBytes(blob[:coordinateSize])
+	b.FillBytes(blob[coordinateSize:])
+	return blob
+}
+
+// PrivateKey represent an ECDSA private key.
+type PrivateKey struct {
+	ecdsa.PrivateKey
+}
+
+// newPrivateKey returns the private key with scalar d.
+func newPrivateKey(d *big.Int) *PrivateKey {
+	curve := elliptic.P256()
+	x, y := curve.ScalarBaseMult(d.FillBytes(make([]byte, coordinateSize)))
+	return &PrivateKey{ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         d,
+	}}
+}
+
+// NewKey creates a new private key from entropy, which normally is
+// crypto/rand.Reader. Tests use a seeded source instead so that every run
+// creates the same keys.
+func NewKey(entropy io.Reader) (*PrivateKey, error) {
+	n := elliptic.P256().Params().N
+	b := make([]byte, coordinateSize)
+	for {
+		if _, err := io.ReadFull(entropy, b); err != nil {
+			return nil, err
+		}
+		d := new(big.Int).SetBytes(b)
+		if d.Sign() > 0 && d.Cmp(n) < 0 {
+			return newPrivateKey(d), nil
+		}
+	}
+}
+
+// Public returns the corresponding public key.
+func (p PrivateKey) Public() []byte {
+	return joinCoordinates(p.PublicKey.X, p.PublicKey.Y)
+}
+
+// Sign returns the signature of blob. The signature nonce is derived from the
+// key and blob, see signRFC6979, so signing is reproducible.
+func (p PrivateKey) Sign(blob []byte) ([]byte, error) {
+	r, s := signRFC6979(&p.PrivateKey, blob)
+	return joinCoordinates(r, s), nil
+}
+
+// PublicKey represents an ECDSA public key.
+type PublicKey struct {
+	ecdsa.PublicKey
+}
+
+// NewPublicKey unpacks pub and creates a corresponding ECDSA public key.
+func NewPublicKey(pub []byte) *PublicKey {
+	l := len(pub) / 2
+	x := new(big.Int).SetBytes(pub[:l])
+	y := new(big.Int).SetBytes(pub[l:])
+	return &PublicKey{ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}
+}
+
+// Verify unpacks signature and verifies the integrity of blob.
+func (p PublicKey) Verify(blob, signature []byte) bool {
+	l := len(signature) / 2
+	r := new(big.Int).SetBytes(signature[:l])
+	s := new(big.Int).SetBytes(signature[l:])
+	return ecdsa.Verify(&p.PublicKey, blob, r, s)
+}
+
+// Key return the []byte representation of an ECDSA public key.
+func (p PublicKey) Key() []byte {
+	return joinCoordinates(p.X, p.Y)
+}
+
+// AddressFormat identifies the encoding of a human readable address.
+type AddressFormat int
+
+const (
+	FormatBase58  AddressFormat = iota // base58(Version+PubKeyHash+Checksum)
+	FormatBech32                       // BIP173 bech32(HRP, PubKeyHash)
+	FormatBech32m                      // BIP350 bech32m(HRP, PubKeyHash)
+)
+
+// String returns the human readable name of the address format.
+func (f AddressFormat) String() string {
+	switch f {
+	case FormatBase58:
+		return "base58"
+	case FormatBech32:
+		return "bech32"
+	case FormatBech32m:
+		return "bech32m"
+	}
+	return fmt.Sprintf("unknown format %d", int(f))
+}
+
+// Address represents all constituent pieces of an address.
+type Address struct {
+	Version    byte          // Version of the address
+	PubKeyHash []byte        // Hash of the public key ripemd160(sha256(pk))
+	Checksum   []byte        // Checksum sha256(sha256(v+pkh))
+	Net        *Params       // Network the address belongs to
+	Format     AddressFormat // Format the address was decoded from
+}
+
+// checksum calculates the checksum of blob by taking the first 4 bytes from
+// the double sha256 of blob.  The checksum uses a double sha256 in order to
+// prevent length-extension attacks.
+func checksum(blob []byte) []byte {
+	chk0 := sha256.Sum256(blob)
+	chk1 := sha256.Sum256(chk0[:])
+	return chk1[0:4]
+}
+
+// ripemd160Sum returns the ripemd160 hash of blob.
+func ripemd160Sum(blob []byte) []byte {
+	r160 := ripemd160.New()
+	_, err := r160.Write(blob)
+	if err != nil {
+		panic(err)
+	}
+	return r160.Sum(nil)
+}
+
+// Hash returns the hash of the public key ripemd160(sha256(pk)).
+func (p PublicKey) Hash() []byte {
+	pksha := sha256.Sum256(p.Key()) // sha256(public key)
+	return ripemd160Sum(pksha[:])   // ripemd160(sha256(public key))
+}
+
+// Address creates an Address structure from a PublicKey for network net.
+func (p PublicKey) Address(net *Params) *Address {
+	pkhash := p.Hash()
+	version := net.PubKeyHashAddrID
+	return &Address{
+		Version:    version,
+		PubKeyHash: pkhash,
+		Checksum:   checksum(append([]byte{version}, pkhash...)),
+		Net:        net,
+	}
+}
+
+// IsForNet returns true if the address belongs to network net.
+func (a Address) IsForNet(net *Params) bool {
+	return a.Version == net.PubKeyHashAddrID
+}
+
+// Verify ensures that the Checksum matches Version and PubKeyHash.
+func (a Address) Verify() error {
+	if !bytes.Equal(checksum(append([]byte{a.Version}, a.PubKeyHash...)),
+		a.Checksum) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// String returns the human readable form of an Address. The process is
+// base58(Version+PubKeyHash+Checksum). The Checksum field is encoded as is so
+// that a corrupt address never prints as a valid one; use Verify or Encode in
+// order to detect it.
+func (a Address) String() string {
+	b := make([]byte, 0, 1+len(a.PubKeyHash)+len(a.Checksum))
+	b = append(b, a.Version)
+	b = append(b, a.PubKeyHash...)
+	return Encode(append(b, a.Checksum...))
+}
+
+// Encode returns the human readable form of an Address in the requested
+// format. Bech32 addresses use the human-readable part of the network the
+// address belongs to and encode the PubKeyHash only; the checksum is part of
+// the bech32 encoding.
+func (a Address) Encode(format AddressFormat) (string, error) {
+	if err := a.Verify(); err != nil {
+		return "", err
+	}
+	switch format {
+	case FormatBase58:
+		return a.String(), nil
+	case FormatBech32, FormatBech32m:
+		net, err := paramsForAddrID(a.Version)
+		if err != nil {
+			return "", err
+		}
+		data, err := convertBits(a.PubKeyHash, 8, 5, true)
+		if err != nil {
+			return "", err
+		}
+		variant := Bech32
+		if format == FormatBech32m {
+			variant = Bech32m
+		}
+		return Bech32Encode(net.Bech32HRP, data, variant)
+	}
+	return "", fmt.Errorf("unknown address format: %v", format)
+}
+
+// isBech32Address returns true if a starts with the bech32 human-readable part
+// of a known network.
+func isBech32Address(a string) bool {
+	a = strings.ToLower(a)
+	sep := strings.LastIndexByte(a, bech32Separator)
+	if sep < 1 {
+		return false
+	}
+	_, err := paramsForHRP(a[:sep])
+	return err == nil
+}
+
+// decodeBech32Address decodes a bech32 or bech32m address into an Address
+// structure. The base58 checksum is recalculated so that the address can be
+// displayed in either format.
+func decodeBech32Address(a string) (*Address, error) {
+	hrp, data, variant, err := Bech32Decode(a)
+	if err != nil {
+		return nil, err
+	}
+	net, err := paramsForHRP(hrp)
+	if err != nil {
+		return nil, err
+	}
+	pkhash, err := convertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkhash) != ripemd160.Size {
+		return nil, ErrInvalidLength
+	}
+	format := FormatBech32
+	if variant == Bech32m {
+		format = FormatBech32m
+	}
+	version := net.PubKeyHashAddrID
+	return &Address{
+		Version:    version,
+		PubKeyHash: pkhash,
+		Checksum:   checksum(append([]byte{version}, pkhash...)),
+		Net:        net,
+		Format:     format,
+	}, nil
+}
+
+// DecodeAddress decodes a human readable address into an Address structure.
+// Both base58 and bech32 addresses are accepted and the Format field reports
+// which one was parsed. An address that starts with the human-readable part of
+// a network is decoded as bech32 first and as base58 if that fails. The
+// network the address belongs to is derived from the version or the bech32
+// human-readable part.
+func DecodeAddress(a string) (*Address, error) {
+	if !isBech32Address(a) {
+		return decodeBase58Address(a)
+	}
+	addr, err := decodeBech32Address(a)
+	if err == nil {
+		return addr, nil
+	}
+
+	// Base58 addresses may start with a human-readable part by accident.
+	if addr, err := decodeBase58Address(a); err == nil {
+		return addr, nil
+	}
+	return nil, err
+}
+
+// decodeBase58Address decodes a base58 address into an Address structure. It
+// recreates the address structure decoding base58 of the provided address
+// which results in the following byte array [version][pub key hash][checksum].
+func decodeBase58Address(a string) (*Address, error) {
+	pkhash, version, err := CheckDecode(a)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkhash) != ripemd160.Size {
+		return nil, ErrInvalidLength
+	}
+	net, err := paramsForAddrID(version)
+	if err != nil {
+		return nil, err
+	}
+	return &Address{
+		Version:    version,
+		PubKeyHash: pkhash,
+		Checksum:   checksum(append([]byte{version}, pkhash...)),
+		Net:        net,
+		Format:     FormatBase58,
+	}, nil
+}
+
+// NewAddress decodes a human readable address for network net. It returns an
+// error if the address belongs to a different network.
+func NewAddress(a string, net *Params) (*Address, error) {
+	addr, err := DecodeAddress(a)
+	if err != nil {
+		return nil, err
+	}
+	if !addr.IsForNet(net) {
+		return nil, fmt.Errorf("address is for %v, not %v", addr.Net, net)
+	}
+	return addr, nil
+}
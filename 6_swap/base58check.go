@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrChecksum is returned when the checksum of a base58check string
+	// does not match its payload.
+	ErrChecksum = errors.New("invalid checksum")
+
+	// ErrInvalidLength is returned when a decoded string is too short to
+	// hold a version and a checksum or when the payload has an unexpected
+	// length.
+	ErrInvalidLength = errors.New("invalid length")
+)
+
+// InvalidCharacterError is returned when a base58 string contains a character
+// that is not part of the alphabet.
+type InvalidCharacterError struct {
+	Position int  // Position of the offending character
+	Char     byte // Offending character
+}
+
+// Error satisfies the error interface.
+func (e InvalidCharacterError) Error() string {
+	return fmt.Sprintf("invalid character %q at position %v", e.Char,
+		e.Position)
+}
+
+// InvalidVersionError is returned when a version byte does not belong to any
+// known network.
+type InvalidVersionError struct {
+	Version byte // Unknown version
+}
+
+// Error satisfies the error interface.
+func (e InvalidVersionError) Error() string {
+	return fmt.Sprintf("invalid version: %v", e.Version)
+}
+
+// CheckEncode prepends version and appends a four byte checksum to input and
+// returns the base58 encoding of the result. The process is
+// base58(version+input+checksum(version+input)).
+func CheckEncode(input []byte, version byte) string {
+	b := make([]byte, 0, 1+len(input)+4)
+	b = append(b, version)
+	b = append(b, input...)
+	b = append(b, checksum(b)...)
+	return Encode(b)
+}
+
+// CheckDecode decodes a string that was encoded with CheckEncode and verifies
+// its checksum. It returns the payload and the version.
+func CheckDecode(input string) ([]byte, byte, error) {
+	decoded, err := DecodeErr(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	l := len(decoded)
+	if l < 5 {
+		return nil, 0, ErrInvalidLength
+	}
+	if !bytes.Equal(checksum(decoded[:l-4]), decoded[l-4:]) {
+		return nil, 0, ErrChecksum
+	}
+	return decoded[1 : l-4], decoded[0], nil
+}
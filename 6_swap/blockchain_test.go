@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wallet is a single key along with the pay-to-pubkey-hash script that
+// locks outputs to it.
+type wallet struct {
+	key      *PrivateKey
+	pkh      []byte // Public key hash
+	pkScript []byte // Pay-to-pubkey-hash script
+}
+
+// newWallet returns a wallet with a new key from entropy.
+func newWallet(t *testing.T, entropy io.Reader) *wallet {
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkh := NewPublicKey(key.Public()).Hash()
+	pkScript, err := PayToPubKeyHashScript(pkh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wallet{key: key, pkh: pkh, pkScript: pkScript}
+}
+
+// pay returns a transaction that spends output op of value atoms, which is
+// locked to the wallet, to pkScript. What is left after paying amount and
+// fee is returned to the wallet.
+func (w *wallet) pay(t *testing.T, op OutPoint, value uint64,
+	pkScript []byte, amount, fee uint64) *Tx {
+
+	tx := &Tx{
+		Inputs: []*TxIn{{PreviousOutPoint: op, Sequence: MaxSequence}},
+		Outputs: []*TxOut{
+			{Value: amount, PkScript: pkScript},
+			{Value: value - amount - fee, PkScript: w.pkScript},
+		},
+	}
+	var err error
+	tx.Inputs[0].SignatureScript, err = SignatureScript(tx, 0,
+		w.pkScript, w.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// mine advances clock by a minute and mines a block with txs onto b that
+// pays the coinbase to pkScript.
+func mine(t *testing.T, b *Blockchain, clock *ManualClock, pkScript []byte,
+	txs ...*Tx) (*Block, error) {
+
+	clock.Advance(time.Minute)
+	blk := b.PrepareBlock(pkScript, txs)
+	return blk, b.Mine(blk)
+}
+
+// coinbaseOutPoint returns the output of the coinbase of blk.
+func coinbaseOutPoint(blk *Block) OutPoint {
+	return OutPoint{Hash: blk.Transactions[0].Hash(), Index: 0}
+}
+
+func TestTransactions(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	params := &SimNetParams
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(params, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := newWallet(t, entropy), newWallet(t, entropy)
+
+	blk, err := mine(t, b, clock, alice.pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coin := coinbaseOutPoint(blk)
+	if b.Utxos().Balance(alice.pkScript) != params.Subsidy {
+		t.Fatalf("subsidy not paid")
+	}
+
+	// The fee goes to the miner.
+	const fee = 1000
+	tx := alice.pay(t, coin, params.Subsidy, bob.pkScript, 1e8, fee)
+	if _, err := mine(t, b, clock, bob.pkScript, tx); err != nil {
+		t.Fatal(err)
+	}
+	got := b.Utxos().Balance(bob.pkScript)
+	if got != params.Subsidy+fee+1e8 {
+		t.Fatalf("bob balance %v", got)
+	}
+	got = b.Utxos().Balance(alice.pkScript)
+	if got != params.Subsidy-fee-1e8 {
+		t.Fatalf("alice balance %v", got)
+	}
+	t.Logf("utxos: %v", b.Utxos().Len())
+
+	change := OutPoint{Hash: tx.Hash(), Index: 1}
+	changeValue := params.Subsidy - fee - 1e8
+	stolen := bob.pay(t, change, changeValue, bob.pkScript, 1e8, 0)
+	greedy := alice.pay(t, change, changeValue, alice.pkScript, 1e8, 0)
+	greedy.Outputs[1].Value++
+	greedy.Inputs[0].SignatureScript, _ = SignatureScript(greedy, 0,
+		alice.pkScript, alice.key)
+	locked := alice.pay(t, change, changeValue, alice.pkScript, 1e8, 0)
+	locked.LockTime = 1000
+	locked.Inputs[0].Sequence = 0
+	locked.Inputs[0].SignatureScript, _ = SignatureScript(locked, 0,
+		alice.pkScript, alice.key)
+	relative := alice.pay(t, change, changeValue, alice.pkScript, 1e8, 0)
+	relative.Inputs[0].Sequence = 10
+	relative.Inputs[0].SignatureScript, _ = SignatureScript(relative, 0,
+		alice.pkScript, alice.key)
+
+	tests := []struct {
+		name string
+		txs  []*Tx
+		err  string
+	}{
+		{"double spend", []*Tx{tx}, "missing or spent output"},
+		{"wrong key", []*Tx{stolen}, "verify failed"},
+		{"overspend", []*Tx{greedy}, "exceed inputs"},
+		{"lock time", []*Tx{locked}, "lock time 1000 not reached"},
+		{"relative lock time", []*Tx{relative}, "relative lock time"},
+		{
+			"spent twice in block",
+			[]*Tx{
+				alice.pay(t, change, changeValue,
+					alice.pkScript, 1e8, 0),
+				alice.pay(t, change, changeValue,
+					bob.pkScript, 1e8, 0),
+			},
+			"missing or spent output",
+		},
+	}
+	for _, test := range tests {
+		_, err := mine(t, b, clock, bob.pkScript, test.txs...)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+
+	// The coinbase may not pay more than subsidy and fees.
+	clock.Advance(time.Minute)
+	blk = b.PrepareBlock(bob.pkScript, nil)
+	blk.Transactions[0].Outputs[0].Value++
+	if err := b.Mine(blk); err == nil {
+		t.Fatalf("oversized coinbase accepted")
+	}
+
+	// Coinbase outputs that wrap around to less than the subsidy.
+	blk = b.PrepareBlock(bob.pkScript, nil)
+	blk.Transactions[0].Outputs = []*TxOut{
+		{Value: math.MaxUint64, PkScript: bob.pkScript},
+		{Value: 1, PkScript: bob.pkScript},
+	}
+	err = b.Mine(blk)
+	if err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Fatalf("overflowing coinbase: %v", err)
+	}
+
+	// Spending outputs of earlier transactions in the same block is fine.
+	next := alice.pay(t, change, changeValue, alice.pkScript, 1e8, 0)
+	chained := alice.pay(t, OutPoint{Hash: next.Hash(), Index: 0}, 1e8,
+		bob.pkScript, 5e7, 0)
+	_, err = mine(t, b, clock, bob.pkScript, next, chained)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spender, err := b.FindSpend(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(spender.Hash()) != string(next.Hash()) {
+		t.Fatalf("wrong spender")
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The engine verifies that an input may spend an output by executing the
+// signature script of the input followed by the public key script of the
+// output on a single stack. The signature script may only push data, so all
+// spending conditions are expressed by the public key script.
+
+var (
+	// ErrScriptFalse is returned when a script completes without leaving
+	// true on the stack.
+	ErrScriptFalse = errors.New("script returned false")
+)
+
+// Engine executes scripts one opcode at a time.
+type Engine struct {
+	// Trace, when set, receives a line for every executed opcode that
+	// shows the position in the script and the stack after execution.
+	Trace io.Writer
+
+	tx       *Tx              // Spending transaction
+	idx      int              // Index of the input that is verified
+	pkScript []byte           // Public key script of the spent output
+	scripts  [][]parsedOpcode // Signature script and public key script
+	script   int              // Index of the executing script
+	pc       int              // Index of the next opcode in the script
+	stack    [][]byte         // Data stack, the last element is the top
+	cond     []bool           // Execution state of nested OP_IFs
+}
+
+// NewEngine returns an engine that verifies that input idx of tx may spend an
+// output locked with pkScript.
+func NewEngine(tx *Tx, idx int, pkScript []byte) (*Engine, error) {
+	if idx < 0 || idx >= len(tx.Inputs) {
+		return nil, fmt.Errorf("input %v out of range", idx)
+	}
+	sigScript, err := parseScript(tx.Inputs[idx].SignatureScript)
+	if err != nil {
+		return nil, fmt.Errorf("signature script: %v", err)
+	}
+	for _, op := range sigScript {
+		if !op.isPush() {
+			return nil, fmt.Errorf("signature script is not push only: %v",
+				op)
+		}
+	}
+	pk, err := parseScript(pkScript)
+	if err != nil {
+		return nil, fmt.Errorf("public key script: %v", err)
+	}
+	e := &Engine{
+		tx:       tx,
+		idx:      idx,
+		pkScript: pkScript,
+		scripts:  [][]parsedOpcode{sigScript, pk},
+	}
+	e.skipEmpty()
+	return e, nil
+}
+
+// skipEmpty advances to the next script that has opcodes left.
+func (e *Engine) skipEmpty() {
+	for e.script < len(e.scripts) && e.pc >= len(e.scripts[e.script]) {
+		e.script++
+		e.pc = 0
+	}
+}
+
+// Stack returns a copy of the stack, bottom first.
+func (e *Engine) Stack() [][]byte {
+	stack := make([][]byte, len(e.stack))
+	copy(stack, e.stack)
+	return stack
+}
+
+// executing returns true if all enclosing conditionals are true.
+func (e *Engine) executing() bool {
+	for _, c := range e.cond {
+		if !c {
+			return false
+		}
+	}
+	return true
+}
+
+// push pushes data on the stack.
+func (e *Engine) push(data []byte) error {
+	if len(data) > maxScriptElementSize {
+		return fmt.Errorf("element too large: %v", len(data))
+	}
+	if len(e.stack)+1 > maxStackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	e.stack = append(e.stack, data)
+	return nil
+}
+
+// pushBool pushes 1 for true and the empty array for false.
+func (e *Engine) pushBool(b bool) error {
+	if b {
+		return e.push([]byte{1})
+	}
+	return e.push(nil)
+}
+
+// pop removes and returns the top of the stack.
+func (e *Engine) pop() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	top := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return top, nil
+}
+
+// peek returns the top of the stack without removing it.
+func (e *Engine) peek() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	return e.stack[len(e.stack)-1], nil
+}
+
+// popNum removes the top of the stack and decodes it as a number.
+func (e *Engine) popNum() (int64, error) {
+	b, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	return makeScriptNum(b, maxScriptNumLen)
+}
+
+// asBool returns the truth value of a stack element. All zeros, including
+// negative zero, and the empty array are false.
+func asBool(b []byte) bool {
+	for i, c := range b {
+		if c != 0 {
+			// Negative zero.
+			if i == len(b)-1 && c == 0x80 {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Step executes the next opcode and returns true once all scripts have been
+// executed.
+func (e *Engine) Step() (bool, error) {
+	if e.script >= len(e.scripts) {
+		return true, nil
+	}
+	op := e.scripts[e.script][e.pc]
+	if err := e.execute(op); err != nil {
+		return false, fmt.Errorf("script %v, opcode %v (%v): %v", e.script,
+			e.pc, op, err)
+	}
+	if e.Trace != nil {
+		name := op.String()
+		if op.data != nil {
+			name = shortHex(op.data)
+		}
+		fmt.Fprintf(e.Trace, "%v:%-3v %-24v %v\n", e.script, e.pc, name,
+			e.stackString())
+	}
+
+	e.pc++
+	if e.pc < len(e.scripts[e.script]) {
+		return false, nil
+	}
+	if len(e.cond) != 0 {
+		return false, fmt.Errorf("script %v: unbalanced conditional",
+			e.script)
+	}
+	e.skipEmpty()
+	return e.script >= len(e.scripts), nil
+}
+
+// Execute runs all scripts and returns nil if they left true on the stack.
+func (e *Engine) Execute() error {
+	for {
+		done, err := e.Step()
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	top, err := e.peek()
+	if err != nil || !asBool(top) {
+		return ErrScriptFalse
+	}
+	return nil
+}
+
+// shortHex returns b in hex with the middle of long values elided to keep
+// traces readable.
+func shortHex(b []byte) string {
+	switch {
+	case len(b) == 0:
+		return "''"
+	case len(b) > 20:
+		return fmt.Sprintf("%x..%x", b[:4], b[len(b)-4:])
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// stackString returns the stack in hex, bottom first.
+func (e *Engine) stackString() string {
+	s := make([]string, 0, len(e.stack))
+	for _, v := range e.stack {
+		s = append(s, shortHex(v))
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// execute executes a single opcode.
+func (e *Engine) execute(op parsedOpcode) error {
+	// Conditionals are tracked even in branches that are not executed.
+	switch op.opcode {
+	case OP_IF, OP_NOTIF:
+		branch := false
+		if e.executing() {
+			top, err := e.pop()
+			if err != nil {
+				return err
+			}
+			branch = asBool(top) == (op.opcode == OP_IF)
+		}
+		e.cond = append(e.cond, branch)
+		return nil
+	case OP_ELSE:
+		if len(e.cond) == 0 {
+			return fmt.Errorf("OP_ELSE without OP_IF")
+		}
+		e.cond[len(e.cond)-1] = !e.cond[len(e.cond)-1]
+		return nil
+	case OP_ENDIF:
+		if len(e.cond) == 0 {
+			return fmt.Errorf("OP_ENDIF without OP_IF")
+		}
+		e.cond = e.cond[:len(e.cond)-1]
+		return nil
+	}
+	if !e.executing() {
+		return nil
+	}
+
+	switch {
+	case op.opcode == OP_0:
+		return e.push(nil)
+	case op.opcode <= OP_PUSHDATA2:
+		return e.push(op.data)
+	case op.opcode == OP_1NEGATE:
+		return e.push(scriptNum(-1))
+	case isSmallInt(op.opcode):
+		return e.push(scriptNum(int64(smallInt(op.opcode))))
+	}
+
+	switch op.opcode {
+	case OP_NOP:
+		return nil
+
+	case OP_VERIFY:
+		return e.verify()
+
+	case OP_RETURN:
+		return fmt.Errorf("OP_RETURN executed")
+
+	case OP_DROP:
+		_, err := e.pop()
+		return err
+
+	case OP_DUP:
+		top, err := e.peek()
+		if err != nil {
+			return err
+		}
+		return e.push(top)
+
+	case OP_SWAP:
+		if len(e.stack) < 2 {
+			return fmt.Errorf("stack underflow")
+		}
+		l := len(e.stack)
+		e.stack[l-1], e.stack[l-2] = e.stack[l-2], e.stack[l-1]
+		return nil
+
+	case OP_SIZE:
+		top, err := e.peek()
+		if err != nil {
+			return err
+		}
+		return e.push(scriptNum(int64(len(top))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if err := e.pushBool(bytes.Equal(a, b)); err != nil {
+			return err
+		}
+		if op.opcode == OP_EQUALVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case OP_SHA256:
+		top, err := e.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(top)
+		return e.push(hash[:])
+
+	case OP_HASH160:
+		top, err := e.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(top)
+		return e.push(ripemd160Sum(hash[:]))
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pk, err := e.pop()
+		if err != nil {
+			return err
+		}
+		sig, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if err := e.pushBool(e.checkSig(sig, pk)); err != nil {
+			return err
+		}
+		if op.opcode == OP_CHECKSIGVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		ok, err := e.checkMultiSig()
+		if err != nil {
+			return err
+		}
+		if err := e.pushBool(ok); err != nil {
+			return err
+		}
+		if op.opcode == OP_CHECKMULTISIGVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case OP_CHECKLOCKTIMEVERIFY:
+		return e.checkLockTime()
+
+	case OP_CHECKSEQUENCEVERIFY:
+		return e.checkSequence()
+	}
+
+	return fmt.Errorf("invalid opcode")
+}
+
+// verify removes the top of the stack and fails if it is false.
+func (e *Engine) verify() error {
+	top, err := e.pop()
+	if err != nil {
+		return err
+	}
+	if !asBool(top) {
+		return fmt.Errorf("verify failed")
+	}
+	return nil
+}
+
+// checkSig returns true if sig is a valid signature of the transaction by the
+// owner of public key pk.
+func (e *Engine) checkSig(sig, pk []byte) bool {
+	if len(sig) != 2*coordinateSize || len(pk) != 2*coordinateSize {
+		return false
+	}
+	hash := e.tx.SignatureHash(e.idx, e.pkScript)
+	return NewPublicKey(pk).Verify(hash, sig)
+}
+
+// checkMultiSig pops <sig>... <m> <pubkey>... <n> and returns true if the m
+// signatures belong to m of the n public keys. Signatures must be in the same
+// order as their public keys.
+func (e *Engine) checkMultiSig() (bool, error) {
+	n, err := e.popNum()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 || n > maxPubKeysPerMultiSig {
+		return false, fmt.Errorf("invalid public key count: %v", n)
+	}
+	pubKeys := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		if pubKeys[i], err = e.pop(); err != nil {
+			return false, err
+		}
+	}
+	m, err := e.popNum()
+	if err != nil {
+		return false, err
+	}
+	if m < 0 || m > n {
+		return false, fmt.Errorf("invalid signature count: %v", m)
+	}
+	sigs := make([][]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		if sigs[i], err = e.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	// Every public key is tried once, so there must be at least as many
+	// public keys left as there are signatures left.
+	for s, k := 0, 0; s < len(sigs); k++ {
+		if len(pubKeys)-k < len(sigs)-s {
+			return false, nil
+		}
+		if e.checkSig(sigs[s], pubKeys[k]) {
+			s++
+		}
+	}
+	return true, nil
+}
+
+// checkLockTime fails unless the lock time of the transaction is at least the
+// top of the stack, which is left on the stack. Both must be block heights or
+// both must be timestamps and the input must not opt out of lock times.
+func (e *Engine) checkLockTime() error {
+	top, err := e.peek()
+	if err != nil {
+		return err
+	}
+	lockTime, err := makeScriptNum(top, maxLockTimeNumLen)
+	if err != nil {
+		return err
+	}
+	if lockTime < 0 {
+		return fmt.Errorf("negative lock time: %v", lockTime)
+	}
+	txLockTime := int64(e.tx.LockTime)
+	if (lockTime < LockTimeThreshold) != (txLockTime < LockTimeThreshold) {
+		return fmt.Errorf("lock time type mismatch: %v vs %v", lockTime,
+			txLockTime)
+	}
+	if lockTime > txLockTime {
+		return fmt.Errorf("lock time %v not reached: %v", lockTime,
+			txLockTime)
+	}
+	if e.tx.Inputs[e.idx].Sequence == MaxSequence {
+		return fmt.Errorf("input sequence disables lock time")
+	}
+	return nil
+}
+
+// checkSequence fails unless the sequence of the input is at least the top of
+// the stack, which is left on the stack.
+func (e *Engine) checkSequence() error {
+	top, err := e.peek()
+	if err != nil {
+		return err
+	}
+	sequence, err := makeScriptNum(top, maxLockTimeNumLen)
+	if err != nil {
+		return err
+	}
+	if sequence < 0 {
+		return fmt.Errorf("negative sequence: %v", sequence)
+	}
+	txSequence := int64(e.tx.Inputs[e.idx].Sequence)
+	if txSequence == MaxSequence {
+		return fmt.Errorf("input sequence disables relative lock time")
+	}
+	if sequence > txSequence {
+		return fmt.Errorf("sequence %v not reached: %v", sequence,
+			txSequence)
+	}
+	return nil
+}
+
+// VerifyInput returns nil if input idx of tx may spend an output locked with
+// pkScript.
+func VerifyInput(tx *Tx, idx int, pkScript []byte) error {
+	e, err := NewEngine(tx, idx, pkScript)
+	if err != nil {
+		return err
+	}
+	return e.Execute()
+}
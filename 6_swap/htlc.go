@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// A hash time locked contract (HTLC) pays to a recipient that reveals the
+// preimage of a hash, the secret, or back to the sender once a lock time has
+// passed. Two HTLCs on two chains that are locked with the same hash make an
+// atomic swap: the initiator picks the secret and redeems the participant's
+// contract, which reveals the secret on chain so that the participant can
+// redeem the initiator's contract. The initiator's lock time is the longer one
+// so that the participant always has time to redeem before the initiator can
+// refund.
+
+// secretSize is the size of an HTLC secret.
+const secretSize = 32
+
+// HTLC holds the parameters of a hash time locked contract.
+type HTLC struct {
+	SecretHash    []byte // sha256 of the secret
+	RecipientHash []byte // Public key hash of the recipient
+	RefundHash    []byte // Public key hash of the sender
+	LockTime      int64  // Height or timestamp that allows a refund
+}
+
+// Script returns the public key script of the contract:
+//
+//	OP_IF
+//		OP_SIZE 32 OP_EQUALVERIFY
+//		OP_SHA256 <secret hash> OP_EQUALVERIFY
+//		OP_DUP OP_HASH160 <recipient hash>
+//	OP_ELSE
+//		<lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP
+//		OP_DUP OP_HASH160 <refund hash>
+//	OP_ENDIF
+//	OP_EQUALVERIFY OP_CHECKSIG
+func (h HTLC) Script() ([]byte, error) {
+	return NewScriptBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SIZE).AddInt64(secretSize).AddOp(OP_EQUALVERIFY).
+		AddOp(OP_SHA256).AddData(h.SecretHash).AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).AddOp(OP_HASH160).AddData(h.RecipientHash).
+		AddOp(OP_ELSE).
+		AddInt64(h.LockTime).AddOp(OP_CHECKLOCKTIMEVERIFY).
+		AddOp(OP_DROP).
+		AddOp(OP_DUP).AddOp(OP_HASH160).AddData(h.RefundHash).
+		AddOp(OP_ENDIF).
+		AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// ParseHTLC returns the contract that script locks an output with. A
+// participant audits the initiator's contract with it before locking funds.
+func ParseHTLC(script []byte) (*HTLC, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) != 20 {
+		return nil, fmt.Errorf("not an HTLC: %v opcodes", len(ops))
+	}
+	var h HTLC
+	h.SecretHash = ops[5].data
+	h.RecipientHash = ops[9].data
+	h.RefundHash = ops[16].data
+	if isSmallInt(ops[11].opcode) {
+		h.LockTime = int64(smallInt(ops[11].opcode))
+	} else {
+		h.LockTime, err = makeScriptNum(ops[11].data, maxLockTimeNumLen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The contract must be identical to the one the parameters produce.
+	expected, err := h.Script()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(script, expected) {
+		return nil, fmt.Errorf("not an HTLC")
+	}
+	return &h, nil
+}
+
+// NewSecret returns a secret read from entropy along with its hash.
+func NewSecret(entropy io.Reader) ([]byte, []byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := io.ReadFull(entropy, secret); err != nil {
+		return nil, nil, err
+	}
+	hash := sha256.Sum256(secret)
+	return secret, hash[:], nil
+}
+
+// RedeemScript returns the signature script that spends the HTLC output
+// contract with input idx of tx by revealing secret:
+//
+//	<signature> <pubkey> <secret> OP_1
+func RedeemScript(tx *Tx, idx int, contract []byte, key *PrivateKey,
+	secret []byte) ([]byte, error) {
+
+	sig, err := key.Sign(tx.SignatureHash(idx, contract))
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptBuilder().AddData(sig).AddData(key.Public()).
+		AddData(secret).AddInt64(1).Script()
+}
+
+// RefundScript returns the signature script that refunds the HTLC output
+// contract with input idx of tx once the lock time has passed. The lock time
+// of tx must be at least that of the contract:
+//
+//	<signature> <pubkey> OP_0
+func RefundScript(tx *Tx, idx int, contract []byte,
+	key *PrivateKey) ([]byte, error) {
+
+	sig, err := key.Sign(tx.SignatureHash(idx, contract))
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptBuilder().AddData(sig).AddData(key.Public()).
+		AddInt64(0).Script()
+}
+
+// ExtractSecret returns the secret that a redeem signature script revealed.
+func ExtractSecret(sigScript, secretHash []byte) ([]byte, error) {
+	ops, err := parseScript(sigScript)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		hash := sha256.Sum256(op.data)
+		if len(op.data) == secretSize &&
+			bytes.Equal(hash[:], secretHash) {
+			return op.data, nil
+		}
+	}
+	return nil, fmt.Errorf("secret not found")
+}
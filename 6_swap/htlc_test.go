@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lockContract returns a transaction that spends the coinbase output of
+// coinbase from w into a contract of amount atoms.
+func lockContract(t *testing.T, w *wallet, coinbase *Block, h *HTLC,
+	amount uint64) (*Tx, []byte) {
+
+	contract, err := h.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := coinbase.Transactions[0].Outputs[0].Value
+	return w.pay(t, coinbaseOutPoint(coinbase), value, contract, amount,
+		1000), contract
+}
+
+// spendContract returns a transaction that spends the contract output op of
+// value atoms to w. A nil secret refunds the contract after lockTime.
+func spendContract(t *testing.T, w *wallet, op OutPoint, value uint64,
+	contract, secret []byte, lockTime uint32) *Tx {
+
+	tx := &Tx{
+		Inputs:   []*TxIn{{PreviousOutPoint: op, Sequence: 0}},
+		Outputs:  []*TxOut{{Value: value - 1000, PkScript: w.pkScript}},
+		LockTime: lockTime,
+	}
+	var err error
+	if secret != nil {
+		tx.Inputs[0].SignatureScript, err = RedeemScript(tx, 0,
+			contract, w.key, secret)
+	} else {
+		tx.Inputs[0].SignatureScript, err = RefundScript(tx, 0,
+			contract, w.key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// TestAtomicSwap swaps coins between two independent chains. Alice has coins
+// on chain A and wants Bob's coins on chain B.
+func TestAtomicSwap(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	paramsB := SimNetParams
+	paramsB.GenesisData = []byte("Another coin")
+	clockA := NewManualClock(time.Unix(1600000000, 0))
+	clockB := NewManualClock(time.Unix(1600000000, 0))
+	chainA, err := NewBlockChain(&SimNetParams, clockA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainB, err := NewBlockChain(&paramsB, clockB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := newWallet(t, entropy)
+	bob := newWallet(t, entropy)
+	miner := newWallet(t, entropy)
+
+	aliceCoin, err := mine(t, chainA, clockA, alice.pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobCoin, err := mine(t, chainB, clockB, bob.pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice initiates: she picks the secret and locks 10 coins on chain A
+	// that Bob can redeem with the secret. She can refund them after 48
+	// blocks.
+	const amountA, amountB = 10 * 1e8, 20 * 1e8
+	secret, secretHash, err := NewSecret(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiate, contractA := lockContract(t, alice, aliceCoin, &HTLC{
+		SecretHash:    secretHash,
+		RecipientHash: bob.pkh,
+		RefundHash:    alice.pkh,
+		LockTime:      int64(chainA.Len() + 48),
+	}, amountA)
+	_, err = mine(t, chainA, clockA, miner.pkScript, initiate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractOutA := OutPoint{Hash: initiate.Hash(), Index: 0}
+	asm, _ := Disassemble(contractA)
+	t.Logf("chain A contract: %v", asm)
+
+	// Bob audits the contract on chain A before locking his coins on
+	// chain B with the same secret hash and half the lock time.
+	entry, err := chainA.Utxos().Lookup(contractOutA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditA, err := ParseHTLC(entry.PkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(auditA.RecipientHash, bob.pkh) ||
+		entry.Value != amountA {
+		t.Fatalf("audit failed")
+	}
+	participate, contractB := lockContract(t, bob, bobCoin, &HTLC{
+		SecretHash:    auditA.SecretHash,
+		RecipientHash: alice.pkh,
+		RefundHash:    bob.pkh,
+		LockTime:      int64(chainB.Len() + 24),
+	}, amountB)
+	_, err = mine(t, chainB, clockB, miner.pkScript, participate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractOutB := OutPoint{Hash: participate.Hash(), Index: 0}
+
+	// Nobody can take the coins without the secret and Bob can't refund
+	// early.
+	wrongSecret := bytes.Repeat([]byte{0x42}, secretSize)
+	steal := spendContract(t, alice, contractOutB, amountB, contractB,
+		wrongSecret, 0)
+	_, err = mine(t, chainB, clockB, miner.pkScript, steal)
+	if err == nil || !strings.Contains(err.Error(), "verify failed") {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	refund := spendContract(t, bob, contractOutB, amountB, contractB, nil,
+		uint32(chainB.Len()+24))
+	_, err = mine(t, chainB, clockB, miner.pkScript, refund)
+	if err == nil || !strings.Contains(err.Error(), "not reached") {
+		t.Fatalf("early refund: %v", err)
+	}
+
+	// Alice redeems Bob's contract on chain B and reveals the secret.
+	redeemB := spendContract(t, alice, contractOutB, amountB, contractB,
+		secret, 0)
+	_, err = mine(t, chainB, clockB, miner.pkScript, redeemB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob watches chain B, learns the secret and redeems Alice's contract
+	// on chain A.
+	spend, err := chainB.FindSpend(contractOutB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learned, err := ExtractSecret(spend.Inputs[0].SignatureScript,
+		auditA.SecretHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("secret: %x", learned)
+	redeemA := spendContract(t, bob, contractOutA, amountA, contractA,
+		learned, 0)
+	_, err = mine(t, chainA, clockA, miner.pkScript, redeemA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := chainA.Utxos().Balance(bob.pkScript)
+	if got != amountA-1000 {
+		t.Fatalf("bob received %v on chain A", got)
+	}
+	got = chainB.Utxos().Balance(alice.pkScript)
+	if got != amountB-1000 {
+		t.Fatalf("alice received %v on chain B", got)
+	}
+}
+
+func TestHTLCRefund(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := newWallet(t, entropy)
+	bob := newWallet(t, entropy)
+	miner := newWallet(t, entropy)
+	coin, err := mine(t, b, clock, alice.pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, secretHash, err := NewSecret(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &HTLC{
+		SecretHash:    secretHash,
+		RecipientHash: bob.pkh,
+		RefundHash:    alice.pkh,
+		LockTime:      10,
+	}
+	initiate, contract := lockContract(t, alice, coin, h, 1e8)
+	if _, err := mine(t, b, clock, miner.pkScript, initiate); err != nil {
+		t.Fatal(err)
+	}
+	op := OutPoint{Hash: initiate.Hash(), Index: 0}
+
+	parsed, err := ParseHTLC(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.LockTime != h.LockTime ||
+		!bytes.Equal(parsed.RefundHash, alice.pkh) {
+		t.Fatalf("parsed %+v", parsed)
+	}
+	if _, err := ParseHTLC(alice.pkScript); err == nil {
+		t.Fatalf("pay-to-pubkey-hash parsed as HTLC")
+	}
+
+	// A refund can only be mined once the lock time has passed.
+	early := spendContract(t, alice, op, 1e8, contract, nil, 10)
+	_, err = mine(t, b, clock, miner.pkScript, early)
+	if err == nil || !strings.Contains(err.Error(), "lock time 10 not") {
+		t.Fatalf("early refund: %v", err)
+	}
+	for b.Len() <= int(h.LockTime) {
+		if _, err := mine(t, b, clock, miner.pkScript); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The refund must carry the contract lock time and pay the sender.
+	tests := []struct {
+		name     string
+		wallet   *wallet
+		lockTime uint32
+		err      string
+	}{
+		{"lock time too low", alice, 9, "lock time 10 not reached: 9"},
+		{"recipient", bob, 10, "verify failed"},
+	}
+	for _, test := range tests {
+		refund := spendContract(t, test.wallet, op, 1e8, contract, nil,
+			test.lockTime)
+		_, err := mine(t, b, clock, miner.pkScript, refund)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+	refund := spendContract(t, alice, op, 1e8, contract, nil, 10)
+	if _, err := mine(t, b, clock, miner.pkScript, refund); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("refunded at height %v", b.Len()-1)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBlockLimits(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	params := SimNetParams
+	params.MaxBlockTxs = 4
+	params.MaxBlockSigOps = 30
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&params, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := newWallet(t, entropy), newWallet(t, entropy)
+
+	// Alice pays bob from six coinbases.
+	const fee = 1000
+	var txs []*Tx
+	for i := 0; i < 6; i++ {
+		blk, err := mine(t, b, clock, alice.pkScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, alice.pay(t, coinbaseOutPoint(blk),
+			params.Subsidy, bob.pkScript, 1e8, fee))
+	}
+
+	// Only three transactions fit next to the coinbase. The template
+	// leaves the others, and their fees, for the next block.
+	clock.Advance(time.Minute)
+	blk := b.PrepareBlock(bob.pkScript, txs)
+	t.Logf("template: %v bytes, %v transactions, %v signature operations",
+		blk.Size(), len(blk.Transactions), blk.SigOps())
+	if len(blk.Transactions) != params.MaxBlockTxs {
+		t.Fatalf("%v transactions in template", len(blk.Transactions))
+	}
+	reward := blk.Transactions[0].Outputs[0].Value
+	if reward != params.Subsidy+3*fee {
+		t.Fatalf("coinbase pays %v", reward)
+	}
+	if err := b.Mine(blk); err != nil {
+		t.Fatal(err)
+	}
+	txs = txs[3:]
+
+	manySigOps := *txs[0]
+	manySigOps.Outputs = []*TxOut{{
+		Value:    txs[0].Outputs[0].Value,
+		PkScript: bytes.Repeat([]byte{OP_CHECKMULTISIG}, 2),
+	}}
+	tests := []struct {
+		name   string
+		modify func(blk *Block)
+		err    error
+	}{
+		{
+			"data",
+			func(blk *Block) {
+				blk.Data = make([]byte, params.MaxBlockSize)
+			},
+			ErrBlockSize,
+		},
+		{
+			"transactions",
+			func(blk *Block) {
+				blk.Transactions = append(blk.Transactions,
+					txs[0])
+			},
+			ErrBlockTxs,
+		},
+		{
+			"signature operations",
+			func(blk *Block) {
+				blk.Transactions[1] = &manySigOps
+			},
+			ErrBlockSigOps,
+		},
+	}
+	for _, test := range tests {
+		clock.Advance(time.Minute)
+		blk := b.PrepareBlock(bob.pkScript, txs)
+		test.modify(blk)
+		err := b.Append(blk)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%v: got %v want %v", test.name, err, test.err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+
+	// The remaining transactions fit.
+	blk, err = mine(t, b, clock, bob.pkScript, txs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blk.Transactions) != 4 {
+		t.Fatalf("%v transactions in block", len(blk.Transactions))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The unspent transaction output (UTXO) set contains every output that can
+// still be spent. A block is validated against a view of the set so that a
+// block that turns out to be invalid leaves the set untouched.
+
+// UtxoEntry is an unspent transaction output.
+type UtxoEntry struct {
+	Value    uint64 // Amount in atoms
+	PkScript []byte // Script that locks the output
+	Height   int    // Height of the block that created the output
+	Coinbase bool   // True if the output was created by a coinbase
+}
+
+// outPointKey returns the map key of op.
+func outPointKey(op OutPoint) string {
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], op.Index)
+	return string(op.Hash) + string(index[:])
+}
+
+// UtxoSet contains all unspent transaction outputs.
+type UtxoSet struct {
+	entries map[string]*UtxoEntry
+}
+
+// NewUtxoSet returns an empty UTXO set.
+func NewUtxoSet() *UtxoSet {
+	return &UtxoSet{entries: make(map[string]*UtxoEntry)}
+}
+
+// Lookup returns the unspent output op.
+func (u *UtxoSet) Lookup(op OutPoint) (*UtxoEntry, error) {
+	entry, ok := u.entries[outPointKey(op)]
+	if !ok {
+		return nil, fmt.Errorf("missing or spent output %x:%v", op.Hash,
+			op.Index)
+	}
+	return entry, nil
+}
+
+// Len returns the number of unspent outputs.
+func (u *UtxoSet) Len() int {
+	return len(u.entries)
+}
+
+// Balance returns the sum of all unspent outputs that are locked with
+// pkScript.
+func (u *UtxoSet) Balance(pkScript []byte) uint64 {
+	var balance uint64
+	for _, entry := range u.entries {
+		if string(entry.PkScript) == string(pkScript) {
+			balance += entry.Value
+		}
+	}
+	return balance
+}
+
+// utxoView records the outputs that a block spends and creates on top of a
+// UTXO set.
+type utxoView struct {
+	set   *UtxoSet
+	added map[string]*UtxoEntry
+	spent map[string]struct{}
+}
+
+// newUtxoView returns an empty view on top of set.
+func newUtxoView(set *UtxoSet) *utxoView {
+	return &utxoView{
+		set:   set,
+		added: make(map[string]*UtxoEntry),
+		spent: make(map[string]struct{}),
+	}
+}
+
+// lookup returns the unspent output op as seen by the view.
+func (v *utxoView) lookup(op OutPoint) (*UtxoEntry, error) {
+	key := outPointKey(op)
+	if _, ok := v.spent[key]; ok {
+		return nil, fmt.Errorf("missing or spent output %x:%v", op.Hash,
+			op.Index)
+	}
+	if entry, ok := v.added[key]; ok {
+		return entry, nil
+	}
+	return v.set.Lookup(op)
+}
+
+// connect spends the inputs of tx and adds its outputs.
+func (v *utxoView) connect(tx *Tx, height int, coinbase bool) {
+	if !coinbase {
+		for _, in := range tx.Inputs {
+			key := outPointKey(in.PreviousOutPoint)
+			if _, ok := v.added[key]; ok {
+				delete(v.added, key)
+				continue
+			}
+			v.spent[key] = struct{}{}
+		}
+	}
+	hash := tx.Hash()
+	for i, out := range tx.Outputs {
+		op := OutPoint{Hash: hash, Index: uint32(i)}
+		v.added[outPointKey(op)] = &UtxoEntry{
+			Value:    out.Value,
+			PkScript: out.PkScript,
+			Height:   height,
+			Coinbase: coinbase,
+		}
+	}
+}
+
+// commit applies the view to the UTXO set.
+func (v *utxoView) commit() {
+	for key := range v.spent {
+		delete(v.set.entries, key)
+	}
+	for key, entry := range v.added {
+		v.set.entries[key] = entry
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var Empty [sha256.Size]byte // All zero sha256 value
+
+const (
+	// medianTimeBlocks is the number of previous blocks that are used to
+	// calculate the median time past.
+	medianTimeBlocks = 11
+
+	// maxFutureDrift is how far a block timestamp may be ahead of the
+	// clock.
+	maxFutureDrift = 2 * time.Hour
+)
+
+// encodeUint64 encodes a uint64 to big endian notation. This code uses big
+// endian in order to make the resulting values more readable for humans.
+func encodeUint64(x uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, x)
+	return b
+}
+
+// Block represents a single block in the blockchain. It is linked to the prior
+// block via the PreviousBlockHash. The first transaction of every block is the
+// coinbase that pays the subsidy and fees to the miner.
+type Block struct {
+	Timestamp         int64  // Timestamp block was mined
+	Data              []byte // Blockchain data
+	Transactions      []*Tx  // Coinbase followed by regular transactions
+	PreviousBlockHash []byte // Previous block hash in order link blocks
+	Hash              []byte // PoW hash of this block
+	Nonce             uint64 // Nonce used to calculate Hash
+}
+
+// NewBlock returns a block that is linked to previousBlockHash and stamped
+// with the current time of clock.
+func NewBlock(clock Clock, data, previousBlockHash []byte) Block {
+	timestamp := clock.Now().Unix()
+	return Block{
+		Timestamp:         timestamp,
+		Data:              data,
+		PreviousBlockHash: previousBlockHash,
+	}
+}
+
+// MerkleRoot returns the hash that commits the block to its transactions.
+func (b Block) MerkleRoot() []byte {
+	hashes := make([][]byte, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		hashes = append(hashes, tx.Hash())
+	}
+	return merkleRoot(hashes)
+}
+
+// BlockHeader is everything that the hash of a block commits to. The
+// transactions are represented by their merkle root so that a light client
+// can verify the chain without downloading them.
+type BlockHeader struct {
+	Timestamp         int64  // Timestamp block was mined
+	Data              []byte // Blockchain data
+	MerkleRoot        []byte // Merkle root of the transactions
+	PreviousBlockHash []byte // Previous block hash in order link blocks
+	Hash              []byte // PoW hash of this block
+	Nonce             uint64 // Nonce used to calculate Hash
+}
+
+// Header returns the header of the block.
+func (b Block) Header() BlockHeader {
+	return BlockHeader{
+		Timestamp:         b.Timestamp,
+		Data:              b.Data,
+		MerkleRoot:        b.MerkleRoot(),
+		PreviousBlockHash: b.PreviousBlockHash,
+		Hash:              b.Hash,
+		Nonce:             b.Nonce,
+	}
+}
+
+// serialize returns everything that is hashed except for the nonce.
+func (h BlockHeader) serialize() []byte {
+	t := encodeUint64(uint64(h.Timestamp))
+	return bytes.Join([][]byte{t, h.Data, h.MerkleRoot,
+		h.PreviousBlockHash}, []byte{})
+}
+
+// calculateHash returns the hash of timestamp, data, merkle root, previous
+// block hash and nonce.
+func (h BlockHeader) calculateHash() []byte {
+	hash := sha256.Sum256(append(h.serialize(), encodeUint64(h.Nonce)...))
+	return hash[:]
+}
+
+// Verify ensures that the header is valid by hashing its contents and nonce.
+func (h BlockHeader) Verify() bool {
+	return bytes.Equal(h.calculateHash(), h.Hash)
+}
+
+// CheckProofOfWork returns true if the header hash satisfies difficulty. It
+// does not verify that the hash matches the header contents.
+func (h BlockHeader) CheckProofOfWork(difficulty uint) bool {
+	return new(big.Int).SetBytes(h.Hash).Cmp(powTarget(difficulty)) == -1
+}
+
+// Verify ensures that the block is valid by hashing its contents and nonce.
+func (b Block) Verify() bool {
+	return b.Header().Verify()
+}
+
+// powTarget returns the value that a block hash must be below in order to
+// satisfy difficulty.
+func powTarget(difficulty uint) *big.Int {
+	target := big.NewInt(1)
+	return target.Lsh(target, uint(256-difficulty))
+}
+
+// CheckProofOfWork returns true if the block hash satisfies difficulty. It
+// does not verify that the hash matches the block contents.
+func (b Block) CheckProofOfWork(difficulty uint) bool {
+	return b.Header().CheckProofOfWork(difficulty)
+}
+
+// Mine attempts to mine the block within the provided range. Transactions
+// must be added before mining because they are committed to by the hash.
+func (b *Block) Mine(difficulty uint) error {
+	target := powTarget(difficulty)
+	header := b.Header().serialize()
+	n := make([]byte, 8)
+	bi := big.Int{}
+	for i := uint64(0); i < math.MaxInt64; i++ {
+		binary.BigEndian.PutUint64(n, i)
+		hash := sha256.Sum256(bytes.Join([][]byte{header, n},
+			[]byte{}))
+		bi.SetBytes(hash[:])
+		if bi.Cmp(target) == -1 {
+			b.Hash = hash[:]
+			b.Nonce = i
+			return nil
+		}
+	}
+	return fmt.Errorf("no solution for block")
+}
+
+// Blockchain is the blockchain context that houses an array of blocks.
+type Blockchain struct {
+	params *Params // Network parameters
+	clock  Clock   // Source of the current time
+	blocks []*Block
+	index  map[string]int // Block hash to height
+	utxos  *UtxoSet       // Unspent transaction outputs
+
+	filters       []*Filter // Compact filter of every block
+	filterHeaders [][]byte  // Filter header of every block
+}
+
+// checkBlock returns the first consensus rule that blk at height violates.
+// The limits are checked first because they are the cheapest to check and
+// protect everything after.
+func (b *Blockchain) checkBlock(height int, blk *Block) error {
+	if err := blk.cost().check(b.params); err != nil {
+		return err
+	}
+	previousBlockHash := Empty[:]
+	if height > 0 {
+		previousBlockHash = b.blocks[height-1].Hash
+	}
+	if !bytes.Equal(previousBlockHash, blk.PreviousBlockHash) {
+		return fmt.Errorf("block does not link to previous block %x %x",
+			previousBlockHash, blk.PreviousBlockHash)
+	}
+	if !blk.Verify() {
+		return fmt.Errorf("can't append invalid block")
+	}
+	if err := checkCheckpoint(b.params, height, blk.Hash); err != nil {
+		return err
+	}
+	if !blk.CheckProofOfWork(b.params.Difficulty) {
+		return fmt.Errorf("hash %x does not satisfy difficulty %v",
+			blk.Hash, b.params.Difficulty)
+	}
+	if mtp := b.medianTimePast(height); blk.Timestamp < mtp {
+		return fmt.Errorf("timestamp %v precedes median time past %v",
+			blk.Timestamp, mtp)
+	}
+	maxTimestamp := b.clock.Now().Add(maxFutureDrift).Unix()
+	if blk.Timestamp > maxTimestamp {
+		return fmt.Errorf("timestamp %v is more than %v ahead of the "+
+			"clock", blk.Timestamp, maxFutureDrift)
+	}
+	return nil
+}
+
+// checkTx verifies that tx may be included in a block at height and returns
+// its fee. All inputs must spend unspent outputs of view and satisfy their
+// scripts and relative time locks. A sequence other than MaxSequence is the
+// number of blocks that the spent output must be buried by. The scripts are
+// only executed if verifyScripts is set.
+func (b *Blockchain) checkTx(view *utxoView, height int, tx *Tx,
+	verifyScripts bool) (uint64, error) {
+
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return 0, fmt.Errorf("transaction without inputs or outputs")
+	}
+	if tx.IsCoinbase() {
+		return 0, fmt.Errorf("coinbase is not the first transaction")
+	}
+	if mtp := b.medianTimePast(height); !tx.IsFinal(height, mtp) {
+		return 0, fmt.Errorf("lock time %v not reached", tx.LockTime)
+	}
+
+	var in, out uint64
+	spent := make(map[string]struct{}, len(tx.Inputs))
+	for i, txIn := range tx.Inputs {
+		key := outPointKey(txIn.PreviousOutPoint)
+		if _, ok := spent[key]; ok {
+			return 0, fmt.Errorf("input %v: output spent twice", i)
+		}
+		spent[key] = struct{}{}
+		entry, err := view.lookup(txIn.PreviousOutPoint)
+		if err != nil {
+			return 0, fmt.Errorf("input %v: %v", i, err)
+		}
+		if txIn.Sequence != MaxSequence &&
+			height-entry.Height < int(txIn.Sequence) {
+			return 0, fmt.Errorf("input %v: relative lock time "+
+				"%v not reached", i, txIn.Sequence)
+		}
+		if verifyScripts {
+			err := VerifyInput(tx, i, entry.PkScript)
+			if err != nil {
+				return 0, fmt.Errorf("input %v: %v", i, err)
+			}
+		}
+		in += entry.Value
+	}
+	for _, txOut := range tx.Outputs {
+		if out+txOut.Value < out {
+			return 0, fmt.Errorf("output value overflow")
+		}
+		out += txOut.Value
+	}
+	if out > in {
+		return 0, fmt.Errorf("outputs %v exceed inputs %v", out, in)
+	}
+	return in - out, nil
+}
+
+// checkTransactions verifies the transactions of blk at height against view
+// and connects them to it. See checkTx for verifyScripts.
+func (b *Blockchain) checkTransactions(view *utxoView, height int,
+	blk *Block, verifyScripts bool) error {
+
+	txs := blk.Transactions
+	if len(txs) == 0 || !txs[0].IsCoinbase() {
+		return fmt.Errorf("block does not start with a coinbase")
+	}
+	coinbase := txs[0]
+	if coinbase.Inputs[0].PreviousOutPoint.Index != uint32(height) {
+		return fmt.Errorf("coinbase height %v, expected %v",
+			coinbase.Inputs[0].PreviousOutPoint.Index, height)
+	}
+
+	var fees uint64
+	for i, tx := range txs[1:] {
+		fee, err := b.checkTx(view, height, tx, verifyScripts)
+		if err != nil {
+			return fmt.Errorf("transaction %v: %v", i+1, err)
+		}
+		if fees+fee < fees {
+			return fmt.Errorf("fee overflow")
+		}
+		fees += fee
+		view.connect(tx, height, false)
+	}
+
+	var reward uint64
+	for _, out := range coinbase.Outputs {
+		if reward+out.Value < reward {
+			return fmt.Errorf("coinbase value overflow")
+		}
+		reward += out.Value
+	}
+	maxReward := b.params.Subsidy + fees
+	if maxReward < fees {
+		return fmt.Errorf("coinbase maximum overflow")
+	}
+	if reward > maxReward {
+		return fmt.Errorf("coinbase pays %v, maximum is %v", reward,
+			maxReward)
+	}
+	view.connect(coinbase, height, true)
+	return nil
+}
+
+// Append adds a block, if valid, to the end of the blockchain. Every
+// transaction must spend unspent outputs and the coinbase may not pay more
+// than the subsidy and the fees of the block.
+func (b *Blockchain) Append(blk *Block) error {
+	return b.append(blk, true)
+}
+
+// append adds blk, if valid, to the end of the blockchain. The input scripts
+// are only executed if verifyScripts is set.
+func (b *Blockchain) append(blk *Block, verifyScripts bool) error {
+	height := len(b.blocks)
+	if err := b.checkBlock(height, blk); err != nil {
+		return err
+	}
+	view := newUtxoView(b.utxos)
+	err := b.checkTransactions(view, height, blk, verifyScripts)
+	if err != nil {
+		return err
+	}
+	view.commit()
+	b.connectFilter(blk)
+	if b.index == nil {
+		b.index = make(map[string]int)
+	}
+	b.index[string(blk.Hash)] = height
+	b.blocks = append(b.blocks, blk)
+	return nil
+}
+
+// NewCoinbase returns the coinbase of the block at height that pays value to
+// pkScript.
+func NewCoinbase(height int, value uint64, pkScript []byte) *Tx {
+	return &Tx{
+		Inputs: []*TxIn{{
+			PreviousOutPoint: OutPoint{Index: uint32(height)},
+			Sequence:         MaxSequence,
+		}},
+		Outputs: []*TxOut{{Value: value, PkScript: pkScript}},
+	}
+}
+
+// fee returns the fee of tx or 0 if tx doesn't spend unspent outputs.
+func (b *Blockchain) fee(tx *Tx) uint64 {
+	var in, out uint64
+	for _, txIn := range tx.Inputs {
+		entry, err := b.utxos.Lookup(txIn.PreviousOutPoint)
+		if err != nil {
+			return 0
+		}
+		in += entry.Value
+	}
+	for _, txOut := range tx.Outputs {
+		out += txOut.Value
+	}
+	if out > in {
+		return 0
+	}
+	return in - out
+}
+
+// PrepareBlock returns a block template based on the current height of the
+// blockchain that contains txs. Transactions are included in order until the
+// next one would exceed a block limit; the remaining ones are left for a later
+// block. The coinbase pays the subsidy and the fees of the included
+// transactions to pkScript. The timestamp is raised to the median time past if
+// the clock is behind it so that the block is acceptable.
+func (b *Blockchain) PrepareBlock(pkScript []byte, txs []*Tx) *Block {
+	var previousBlockHash []byte
+	if len(b.blocks) == 0 {
+		// Genesis
+		previousBlockHash = Empty[:]
+	} else {
+		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
+	}
+	blk := NewBlock(b.clock, nil, previousBlockHash)
+	if mtp := b.medianTimePast(len(b.blocks)); blk.Timestamp < mtp {
+		blk.Timestamp = mtp
+	}
+
+	coinbase := NewCoinbase(len(b.blocks), b.params.Subsidy, pkScript)
+	blk.Transactions = []*Tx{coinbase}
+	cost := blk.cost()
+	for _, tx := range txs {
+		next := cost
+		next.add(tx)
+		if next.check(b.params) != nil {
+			break
+		}
+		cost = next
+		coinbase.Outputs[0].Value += b.fee(tx)
+		blk.Transactions = append(blk.Transactions, tx)
+	}
+	return &blk
+}
+
+// Mine mines blk and appends it to the blockchain.
+func (b *Blockchain) Mine(blk *Block) error {
+	if err := blk.Mine(b.params.Difficulty); err != nil {
+		return err
+	}
+	return b.Append(blk)
+}
+
+// Block returns a copy of the block at the specified block height.
+func (b Blockchain) Block(block int) (Block, error) {
+	if block < 0 || block >= len(b.blocks) {
+		return Block{}, fmt.Errorf("invalid block: %v", block)
+	}
+	return *b.blocks[block], nil
+}
+
+// BlockByHash returns a copy of the block with the specified hash along with
+// its height.
+func (b Blockchain) BlockByHash(hash []byte) (Block, int, error) {
+	height, ok := b.index[string(hash)]
+	if !ok {
+		return Block{}, 0, fmt.Errorf("block not found: %x", hash)
+	}
+	return *b.blocks[height], height, nil
+}
+
+// Tip returns a copy of the last block along with its height.
+func (b Blockchain) Tip() (Block, int, error) {
+	if len(b.blocks) == 0 {
+		return Block{}, 0, fmt.Errorf("empty blockchain")
+	}
+	height := len(b.blocks) - 1
+	return *b.blocks[height], height, nil
+}
+
+// FindSpend returns the transaction that spends op.
+func (b Blockchain) FindSpend(op OutPoint) (*Tx, error) {
+	key := outPointKey(op)
+	for i := len(b.blocks) - 1; i >= 0; i-- {
+		for _, tx := range b.blocks[i].Transactions {
+			for _, in := range tx.Inputs {
+				if outPointKey(in.PreviousOutPoint) == key {
+					return tx, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("output not spent: %x:%v", op.Hash, op.Index)
+}
+
+// medianTimePast returns the median timestamp of the up to medianTimeBlocks
+// blocks that precede height.
+func (b Blockchain) medianTimePast(height int) int64 {
+	start := height - medianTimeBlocks
+	if start < 0 {
+		start = 0
+	}
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for _, blk := range b.blocks[start:height] {
+		timestamps = append(timestamps, blk.Timestamp)
+	}
+	return median(timestamps)
+}
+
+// median returns the median of timestamps or 0 if there are none.
+func median(timestamps []int64) int64 {
+	if len(timestamps) == 0 {
+		return 0
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2]
+}
+
+// Len returns the current blockchain height.
+func (b Blockchain) Len() int {
+	return len(b.blocks)
+}
+
+// Params returns the network parameters of the blockchain.
+func (b Blockchain) Params() *Params {
+	return b.params
+}
+
+// Utxos returns the unspent transaction outputs.
+func (b Blockchain) Utxos() *UtxoSet {
+	return b.utxos
+}
+
+// NewBlockChain returns a blockchain context that has a genesis block. The
+// genesis data and the difficulty are taken from the network parameters. The
+// genesis coinbase is unspendable. All timestamps are taken from clock.
+func NewBlockChain(params *Params, clock Clock) (*Blockchain, error) {
+	b := &Blockchain{
+		params: params,
+		clock:  clock,
+		utxos:  NewUtxoSet(),
+	}
+	blk := b.PrepareBlock([]byte{OP_RETURN}, nil)
+	blk.Data = params.GenesisData
+	if err := b.Mine(blk); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
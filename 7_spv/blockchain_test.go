@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoinbaseOverflow(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	miner := newWallet(t, entropy)
+
+	// The outputs wrap around to zero, which is less than the subsidy.
+	clock.Advance(time.Minute)
+	blk := b.PrepareBlock(miner.pkScript, nil)
+	blk.Transactions[0].Outputs = []*TxOut{
+		{Value: math.MaxUint64, PkScript: miner.pkScript},
+		{Value: 1, PkScript: miner.pkScript},
+	}
+	err = b.Mine(blk)
+	if err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Fatalf("overflowing coinbase: %v", err)
+	}
+	t.Logf("%v", err)
+	if b.Len() != 1 || b.Utxos().Balance(miner.pkScript) != 0 {
+		t.Fatalf("overflowing coinbase appended")
+	}
+}
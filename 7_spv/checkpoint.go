@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A node that imports a long chain spends nearly all of its time verifying
+// signatures. Checkpoints and assume-valid are two ways to trade some of that
+// work for trust in the software that ships the parameters.
+//
+// A checkpoint pins the hash of the block at a height. Every chain that
+// contains a different block at that height is rejected, which protects a
+// syncing node from long forks that start below the checkpoint.
+//
+// The assume-valid block is a block that is known to be valid. The scripts of
+// the assume-valid block and its ancestors are not executed, however their
+// proof-of-work, links, timestamps, limits and spent outputs are still checked
+// so the UTXO set is exactly the same as after a full verification. Nothing is
+// skipped for blocks that are not ancestors of the assume-valid block.
+//
+// The networks ship without checkpoints and without an assume-valid block
+// because their genesis block is mined when the blockchain is created. A real
+// network hardcodes both in its parameters with every release.
+
+// ErrCheckpoint is returned for blocks and headers that conflict with a
+// checkpoint.
+var ErrCheckpoint = errors.New("checkpoint mismatch")
+
+// Checkpoint is the hash of the block at a height.
+type Checkpoint struct {
+	Height int    // Height of the block
+	Hash   []byte // Hash of the block
+}
+
+// checkCheckpoint returns an error that wraps ErrCheckpoint if params has a
+// checkpoint at height with a hash other than hash.
+func checkCheckpoint(params *Params, height int, hash []byte) error {
+	for _, c := range params.Checkpoints {
+		if c.Height == height && !bytes.Equal(c.Hash, hash) {
+			return fmt.Errorf("%w: hash %x, expected %x",
+				ErrCheckpoint, hash, c.Hash)
+		}
+	}
+	return nil
+}
+
+// assumedValid returns the number of leading blocks whose scripts do not have
+// to be executed: the assume-valid block and all blocks before it. Only the
+// assume-valid block itself is hashed here. Its hash commits to its parent,
+// and checkBlock verifies every other block before its transactions are
+// checked, so each block that is appended hashes to the link of its child and
+// is a true ancestor of the assume-valid block. Zero is returned if blocks
+// doesn't link the assume-valid block to the tip.
+func (b *Blockchain) assumedValid(blocks []*Block) int {
+	if len(b.params.AssumeValid) == 0 {
+		return 0
+	}
+	previousBlockHash := Empty[:]
+	if len(b.blocks) > 0 {
+		previousBlockHash = b.blocks[len(b.blocks)-1].Hash
+	}
+	for i, blk := range blocks {
+		if !bytes.Equal(blk.PreviousBlockHash, previousBlockHash) {
+			return 0
+		}
+		if bytes.Equal(blk.Hash, b.params.AssumeValid) {
+			if !blk.Verify() {
+				return 0
+			}
+			return i + 1
+		}
+		previousBlockHash = blk.Hash
+	}
+	return 0
+}
+
+// ImportStats describes the work done by Import.
+type ImportStats struct {
+	Blocks       int           // Number of appended blocks
+	Scripts      int           // Number of executed input scripts
+	AssumedValid int           // Number of input scripts that were skipped
+	Elapsed      time.Duration // Time the import took
+}
+
+// Rate returns the number of appended blocks per second.
+func (s ImportStats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Blocks) / s.Elapsed.Seconds()
+}
+
+// Import appends blocks to the end of the blockchain. The blocks are checked
+// like Append does, except that the scripts of the assume-valid block and its
+// ancestors are not executed. Import stops at the first invalid block; the
+// blocks before it remain appended and are reported in the returned stats.
+func (b *Blockchain) Import(blocks []*Block) (*ImportStats, error) {
+	start := time.Now()
+	stats := &ImportStats{}
+	assumed := b.assumedValid(blocks)
+	for i, blk := range blocks {
+		verifyScripts := i >= assumed
+		if err := b.append(blk, verifyScripts); err != nil {
+			stats.Elapsed = time.Since(start)
+			return stats, fmt.Errorf("block %v: %w", len(b.blocks),
+				err)
+		}
+		var inputs int
+		for _, tx := range blk.Transactions[1:] {
+			inputs += len(tx.Inputs)
+		}
+		if verifyScripts {
+			stats.Scripts += inputs
+		} else {
+			stats.AssumedValid += inputs
+		}
+		stats.Blocks++
+	}
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
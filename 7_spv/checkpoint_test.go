@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// chainBlocks returns all blocks of b after genesis.
+func chainBlocks(t *testing.T, b *Blockchain) []*Block {
+	blocks := make([]*Block, 0, b.Len()-1)
+	for i := 1; i < b.Len(); i++ {
+		blk, err := b.Block(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, &blk)
+	}
+	return blocks
+}
+
+func TestImport(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	start := time.Unix(1600000000, 0)
+	clock := NewManualClock(start)
+	b, err := NewBlockChain(&SimNetParams, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := newWallet(t, entropy), newWallet(t, entropy)
+
+	// Every block pays alice and spends the coinbase of its parent.
+	const count = 30
+	blk := mine(t, b, clock, alice.pkScript)
+	for i := 1; i < count; i++ {
+		tx := alice.pay(t, coinbaseOutPoint(blk), SimNetParams.Subsidy,
+			bob.pkScript, 1e8, 0)
+		blk = mine(t, b, clock, alice.pkScript, tx)
+	}
+	blocks := chainBlocks(t, b)
+
+	// A fork of the same genesis that pays bob instead.
+	forkClock := NewManualClock(start)
+	fork, err := NewBlockChain(&SimNetParams, forkClock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		mine(t, fork, forkClock, bob.pkScript)
+	}
+	forkBlocks := chainBlocks(t, fork)
+
+	// newChain returns a blockchain with the genesis block of b and a
+	// clock that has caught up with b.
+	newChain := func(params *Params) *Blockchain {
+		c := NewManualClock(start)
+		chain, err := NewBlockChain(params, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Set(clock.Now())
+		return chain
+	}
+
+	full := SimNetParams
+	assumeValid := SimNetParams
+	assumeValid.AssumeValid = blocks[count-6].Hash
+	unknown := SimNetParams
+	unknown.AssumeValid = forkBlocks[len(forkBlocks)-1].Hash
+	tests := []struct {
+		name    string
+		params  *Params
+		scripts int
+	}{
+		{"full", &full, count - 1},
+		{"assume valid", &assumeValid, 5},
+		{"unknown assume valid", &unknown, count - 1},
+	}
+	var elapsed time.Duration
+	for i, test := range tests {
+		c := newChain(test.params)
+		stats, err := c.Import(blocks)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		t.Logf("%v: %v blocks, %v scripts, %v assumed valid, %v, "+
+			"%.0f blocks/s", test.name, stats.Blocks, stats.Scripts,
+			stats.AssumedValid, stats.Elapsed, stats.Rate())
+		if stats.Blocks != count || stats.Scripts != test.scripts ||
+			stats.Scripts+stats.AssumedValid != count-1 {
+			t.Fatalf("%v: unexpected stats %+v", test.name, stats)
+		}
+		if c.Utxos().Balance(bob.pkScript) != (count-1)*1e8 {
+			t.Fatalf("%v: utxo set differs", test.name)
+		}
+		if i == 0 {
+			elapsed = stats.Elapsed
+		} else if stats.AssumedValid != 0 && stats.Elapsed > 0 {
+			t.Logf("%v: speedup %.1fx", test.name,
+				float64(elapsed)/float64(stats.Elapsed))
+		}
+	}
+
+	// A block that claims to be the assume-valid block without hashing to
+	// it doesn't vouch for a forged ancestor.
+	forgedTx := *blocks[1].Transactions[1]
+	forgedTx.Outputs = []*TxOut{{Value: 1e8, PkScript: alice.pkScript}}
+	forged := *blocks[1]
+	forged.Transactions = []*Tx{forged.Transactions[0], &forgedTx}
+	if err := forged.Mine(SimNetParams.Difficulty); err != nil {
+		t.Fatal(err)
+	}
+	fake := *blocks[2]
+	fake.PreviousBlockHash = forged.Hash
+	claim := SimNetParams
+	claim.AssumeValid = fake.Hash
+	stats, err := newChain(&claim).Import([]*Block{blocks[0], &forged,
+		&fake})
+	if err == nil || stats.Blocks != 1 || stats.AssumedValid != 0 {
+		t.Fatalf("forged block imported: %v", err)
+	}
+	t.Logf("forged: %v", err)
+
+	// Checkpoints reject the fork at the checkpoint height, both as blocks
+	// and as headers.
+	checkpoint := SimNetParams
+	checkpoint.Checkpoints = []Checkpoint{{Height: 5, Hash: blocks[4].Hash}}
+	if _, err := newChain(&checkpoint).Import(blocks); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = newChain(&checkpoint).Import(forkBlocks)
+	if !errors.Is(err, ErrCheckpoint) || stats.Blocks != 4 {
+		t.Fatalf("fork imported: %v", err)
+	}
+	t.Logf("fork: %v", err)
+
+	genesis, _ := b.Block(0)
+	headers, err := NewHeaderChain(&checkpoint, clock, genesis.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forkHeaders := fork.Headers(1)
+	for _, header := range forkHeaders[:4] {
+		if err := headers.Append(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = headers.Append(forkHeaders[4])
+	if !errors.Is(err, ErrCheckpoint) {
+		t.Fatalf("forked header accepted: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Compact block filters, in the spirit of BIP157 and BIP158, let a light
+// client find its transactions without telling a full node which addresses it
+// is interested in. The node builds a filter for every block that contains the
+// public key hashes the block pays to and the outpoints it spends. The client
+// downloads the filters, matches them locally and only downloads the blocks
+// that match.
+//
+// The filters themselves are committed to by a chain of filter headers that
+// is built just like the blockchain:
+//
+//	header[n] = sha256(sha256(hash(filter[n]) || header[n-1]))
+//
+// The client can't build filter headers itself since it doesn't have the
+// blocks. Instead it downloads the filter headers from several peers and only
+// keeps them if all peers agree. A single honest peer is enough to expose a
+// lie; peers that all lie the same way can't be detected. Every filter the
+// client downloads later, from any peer, is verified against its own filter
+// header chain.
+
+// outPointItem returns the filter item of op.
+func outPointItem(op OutPoint) []byte {
+	return []byte(outPointKey(op))
+}
+
+// BlockFilter returns the filter of blk. It contains the public key hash of
+// every pay-to-pubkey-hash output and every outpoint that is spent. The
+// filter is keyed with the first 16 bytes of the block hash.
+func BlockFilter(blk *Block) *Filter {
+	var key [16]byte
+	copy(key[:], blk.Hash)
+
+	var items [][]byte
+	seen := make(map[string]struct{})
+	add := func(item []byte) {
+		if _, ok := seen[string(item)]; ok {
+			return
+		}
+		seen[string(item)] = struct{}{}
+		items = append(items, item)
+	}
+	for _, tx := range blk.Transactions {
+		if !tx.IsCoinbase() {
+			for _, in := range tx.Inputs {
+				add(outPointItem(in.PreviousOutPoint))
+			}
+		}
+		for _, out := range tx.Outputs {
+			if pkh, ok := ExtractPubKeyHash(out.PkScript); ok {
+				add(pkh)
+			}
+		}
+	}
+	return NewFilter(filterP, filterM, key, items)
+}
+
+// FilterHeader returns the filter header that commits to the filter with
+// filterHash and to all previous filters through previousHeader.
+func FilterHeader(filterHash, previousHeader []byte) []byte {
+	return doubleSHA256(append(append([]byte{}, filterHash...),
+		previousHeader...))
+}
+
+// Filter returns the filter of the block at height.
+func (b Blockchain) Filter(height int) (*Filter, error) {
+	if height < 0 || height >= len(b.filters) {
+		return nil, fmt.Errorf("invalid filter: %v", height)
+	}
+	return b.filters[height], nil
+}
+
+// FilterHeader returns the filter header of the block at height.
+func (b Blockchain) FilterHeader(height int) ([]byte, error) {
+	if height < 0 || height >= len(b.filterHeaders) {
+		return nil, fmt.Errorf("invalid filter header: %v", height)
+	}
+	return b.filterHeaders[height], nil
+}
+
+// connectFilter builds the filter of blk and extends the filter header
+// chain.
+func (b *Blockchain) connectFilter(blk *Block) {
+	previousHeader := Empty[:]
+	if l := len(b.filterHeaders); l > 0 {
+		previousHeader = b.filterHeaders[l-1]
+	}
+	filter := BlockFilter(blk)
+	b.filters = append(b.filters, filter)
+	b.filterHeaders = append(b.filterHeaders, FilterHeader(filter.Hash(),
+		previousHeader))
+}
+
+// FilterMatcher tests filters for the items a wallet is interested in.
+type FilterMatcher struct {
+	items [][]byte
+}
+
+// NewFilterMatcher returns a matcher for payments to addresses and for
+// spends of outPoints.
+func NewFilterMatcher(addresses []*Address,
+	outPoints []OutPoint) *FilterMatcher {
+
+	m := &FilterMatcher{}
+	for _, a := range addresses {
+		m.items = append(m.items, a.PubKeyHash)
+	}
+	for _, op := range outPoints {
+		m.items = append(m.items, outPointItem(op))
+	}
+	return m
+}
+
+// Match returns true if the filter probably contains one of the items.
+func (m *FilterMatcher) Match(f *Filter) (bool, error) {
+	return f.MatchAny(m.items)
+}
+
+// FalsePositiveRate returns the probability that a block filter matches
+// although the block contains none of the items. Every item matches by
+// accident with probability 1/M.
+func (m *FilterMatcher) FalsePositiveRate() float64 {
+	return float64(len(m.items)) / filterM
+}
+
+// FilterNode serves filters and blocks to light clients.
+type FilterNode interface {
+	// FilterHeader returns the filter header of the block at height.
+	FilterHeader(height int) ([]byte, error)
+
+	// Filter returns the filter of the block at height.
+	Filter(height int) (*Filter, error)
+
+	// Block returns the block at height.
+	Block(height int) (Block, error)
+}
+
+// SyncFilterHeaders downloads the filter headers of all new blocks in the
+// header chain from every node and appends them to the filter header chain of
+// the client. All nodes must return the same filter headers, which makes it
+// pointless to use a single node.
+func (c *LightClient) SyncFilterHeaders(nodes ...FilterNode) error {
+	if len(nodes) == 0 {
+		return fmt.Errorf("no filter nodes")
+	}
+	for height := len(c.filterHeaders); height < c.headers.Len(); height++ {
+		header, err := nodes[0].FilterHeader(height)
+		if err != nil {
+			return err
+		}
+		for i, node := range nodes[1:] {
+			other, err := node.FilterHeader(height)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(header, other) {
+				return fmt.Errorf("filter header %v: node %v "+
+					"disagrees with node 0", height, i+1)
+			}
+		}
+		c.filterHeaders = append(c.filterHeaders, header)
+	}
+	return nil
+}
+
+// ScanFilters matches the filters of all blocks from height from on and
+// downloads the blocks that match. Only blocks whose filter header has been
+// synced with SyncFilterHeaders are scanned. Every filter is verified against
+// the filter header chain of the client and every block against its header
+// chain; node is not trusted. The filter header only commits to the serialized
+// filter, so the filter is parsed again with the parameters of the client and
+// the key of the block header instead of using the ones node returned.
+func (c *LightClient) ScanFilters(node FilterNode, m *FilterMatcher,
+	from int) ([]Block, error) {
+
+	if from < 0 || from > len(c.filterHeaders) {
+		return nil, fmt.Errorf("invalid height: %v", from)
+	}
+	var blocks []Block
+	for height := from; height < len(c.filterHeaders); height++ {
+		header, err := c.headers.Header(height)
+		if err != nil {
+			return nil, err
+		}
+		served, err := node.Filter(height)
+		if err != nil {
+			return nil, err
+		}
+		var key [16]byte
+		copy(key[:], header.Hash)
+		filter, err := ParseFilter(filterP, filterM, key,
+			served.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("filter %v: %v", height, err)
+		}
+		previousHeader := Empty[:]
+		if height > 0 {
+			previousHeader = c.filterHeaders[height-1]
+		}
+		expected := FilterHeader(filter.Hash(), previousHeader)
+		if !bytes.Equal(c.filterHeaders[height], expected) {
+			return nil, fmt.Errorf("filter %v does not match its "+
+				"header", height)
+		}
+
+		match, err := m.Match(filter)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		blk, err := node.Block(height)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(blk.Hash, header.Hash) || !blk.Verify() {
+			return nil, fmt.Errorf("block %v does not match its "+
+				"header", height)
+		}
+		blocks = append(blocks, blk)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSipHash(t *testing.T) {
+	// Vectors from the SipHash reference implementation with key
+	// 000102...0f and messages 00, 0001, 000102, ...
+	tests := []struct {
+		length int
+		hash   uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+	k0 := uint64(0x0706050403020100)
+	k1 := uint64(0x0f0e0d0c0b0a0908)
+	for _, test := range tests {
+		blob := make([]byte, test.length)
+		for i := range blob {
+			blob[i] = byte(i)
+		}
+		if h := sipHash(k0, k1, blob); h != test.hash {
+			t.Fatalf("length %v: got %x want %x", test.length, h,
+				test.hash)
+		}
+	}
+}
+
+// filterItem returns a distinct filter item for i.
+func filterItem(i int) []byte {
+	item := make([]byte, 8)
+	binary.BigEndian.PutUint64(item, uint64(i))
+	return item
+}
+
+func TestFilter(t *testing.T) {
+	var key [16]byte
+	copy(key[:], "educoin filters!")
+
+	// Use a high false positive rate so that it can be measured.
+	const (
+		p     = 10
+		m     = 1 << p
+		n     = 200
+		tries = 100000
+	)
+	var items [][]byte
+	for i := 0; i < n; i++ {
+		items = append(items, filterItem(i))
+	}
+	f := NewFilter(p, m, key, items)
+	t.Logf("%v items in %v bytes, %.1f bits per item", f.N(),
+		len(f.Bytes()), float64(8*len(f.Bytes()))/n)
+
+	for _, item := range items {
+		match, err := f.Match(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !match {
+			t.Fatalf("%x not matched", item)
+		}
+	}
+
+	falsePositives := 0
+	for i := n; i < n+tries; i++ {
+		match, err := f.Match(filterItem(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / tries
+	t.Logf("false positive rate %.5f, expected %.5f", rate, 1.0/m)
+	if rate < 0.5/m || rate > 2.0/m {
+		t.Fatalf("false positive rate %v out of range", rate)
+	}
+
+	match, err := f.MatchAny([][]byte{filterItem(-1), items[n/2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match {
+		t.Fatalf("MatchAny missed item")
+	}
+	empty := NewFilter(p, m, key, nil)
+	if match, _ := empty.Match(items[0]); match {
+		t.Fatalf("empty filter matched")
+	}
+
+	// A parsed filter matches the same items.
+	parsed, err := ParseFilter(p, m, key, f.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Hash(), f.Hash()) {
+		t.Fatalf("parsed filter differs")
+	}
+	if match, _ := parsed.Match(items[n-1]); !match {
+		t.Fatalf("parsed filter missed item")
+	}
+	if _, err := ParseFilter(p, m, key, nil); err == nil {
+		t.Fatalf("empty serialization parsed")
+	}
+}
+
+// lyingNode serves the blocks of a blockchain along with a different filter
+// for the block at height lie and filter headers that match.
+type lyingNode struct {
+	*Blockchain
+	lie     int
+	filter  *Filter
+	headers [][]byte
+}
+
+// newLyingNode returns a node that serves filter as the filter of the block at
+// height lie.
+func newLyingNode(b *Blockchain, lie int, filter *Filter) *lyingNode {
+	n := &lyingNode{
+		Blockchain: b,
+		lie:        lie,
+		filter:     filter,
+	}
+	previousHeader := Empty[:]
+	for height, filter := range b.filters {
+		if height == lie {
+			filter = n.filter
+		}
+		previousHeader = FilterHeader(filter.Hash(), previousHeader)
+		n.headers = append(n.headers, previousHeader)
+	}
+	return n
+}
+
+// Filter returns the filter of the block at height.
+func (n *lyingNode) Filter(height int) (*Filter, error) {
+	if height == n.lie {
+		return n.filter, nil
+	}
+	return n.Blockchain.Filter(height)
+}
+
+// FilterHeader returns the filter header of the block at height.
+func (n *lyingNode) FilterHeader(height int) ([]byte, error) {
+	if height < 0 || height >= len(n.headers) {
+		return nil, fmt.Errorf("invalid filter header: %v", height)
+	}
+	return n.headers[height], nil
+}
+
+func TestScanFilters(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	params := &SimNetParams
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(params, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := newWallet(t, entropy)
+	bob := newWallet(t, entropy)
+	miner := newWallet(t, entropy)
+
+	// Bob is paid at heights 5 and 15 and spends the first payment at
+	// height 25. All other blocks only pay the miner.
+	var coins []*Block
+	for i := 0; i < 2; i++ {
+		coins = append(coins, mine(t, b, clock, alice.pkScript))
+	}
+	var paid OutPoint
+	for b.Len() < 30 {
+		var txs []*Tx
+		switch b.Len() {
+		case 5, 15:
+			coin := coins[0]
+			coins = coins[1:]
+			tx := alice.pay(t, coinbaseOutPoint(coin),
+				params.Subsidy, bob.pkScript, 1e8, 0)
+			if b.Len() == 5 {
+				paid = OutPoint{Hash: tx.Hash(), Index: 0}
+			}
+			txs = append(txs, tx)
+		case 25:
+			txs = append(txs, bob.pay(t, paid, 1e8, alice.pkScript,
+				5e7, 0))
+		}
+		mine(t, b, clock, miner.pkScript, txs...)
+	}
+
+	client := newLightClient(t, b, clock)
+	if _, err := client.Sync(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// A peer that imported the same chain agrees on the filter headers and
+	// a peer that omits the items of a block does not.
+	peerClock := NewManualClock(time.Unix(1600000000, 0))
+	peer, err := NewBlockChain(params, peerClock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerClock.Set(clock.Now())
+	if _, err := peer.Import(chainBlocks(t, b)); err != nil {
+		t.Fatal(err)
+	}
+	liar := newLyingNode(b, 5, NewFilter(filterP, filterM,
+		b.filters[5].key, nil))
+	other := newLightClient(t, b, clock)
+	if _, err := other.Sync(b); err != nil {
+		t.Fatal(err)
+	}
+	err = other.SyncFilterHeaders(peer, liar)
+	if err == nil || !strings.Contains(err.Error(), "disagrees") {
+		t.Fatalf("lying filter headers accepted: %v", err)
+	}
+	t.Logf("lying filter headers: %v", err)
+	if err := client.SyncFilterHeaders(b, peer); err != nil {
+		t.Fatal(err)
+	}
+
+	matcher := NewFilterMatcher([]*Address{bob.address}, []OutPoint{paid})
+	t.Logf("false positive rate per block: %v",
+		matcher.FalsePositiveRate())
+	blocks, err := client.ScanFilters(b, matcher, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heights []int
+	for _, blk := range blocks {
+		_, height, _ := b.BlockByHash(blk.Hash)
+		heights = append(heights, height)
+	}
+	t.Logf("downloaded %v of %v blocks: %v", len(blocks), b.Len(),
+		heights)
+	if len(heights) != 3 || heights[0] != 5 || heights[1] != 15 ||
+		heights[2] != 25 {
+		t.Fatalf("unexpected matches: %v", heights)
+	}
+
+	// Scanning can resume at any height.
+	blocks, err = client.ScanFilters(b, matcher, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 {
+		t.Fatalf("%v matches from height 20", len(blocks))
+	}
+
+	// A filter that omits an item doesn't match the agreed on header.
+	_, err = client.ScanFilters(liar, matcher, 0)
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("tampered filter accepted: %v", err)
+	}
+
+	// The Golomb parameters aren't committed to by the filter header and
+	// are ignored, a node can't hide a block by tampering with them.
+	p := *b.filters[15]
+	p.p++
+	m := *b.filters[15]
+	m.m /= 2
+	for _, tampered := range []*Filter{&p, &m} {
+		if match, _ := matcher.Match(tampered); match {
+			t.Fatalf("tampered filter matched")
+		}
+		liar := newLyingNode(b, 15, tampered)
+		blocks, err := client.ScanFilters(liar, matcher, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocks) != 2 {
+			t.Fatalf("%v matches with p %v and m %v", len(blocks),
+				tampered.p, tampered.m)
+		}
+	}
+}
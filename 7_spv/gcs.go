@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// A Golomb-coded set (GCS) is a compact probabilistic set, similar to a bloom
+// filter. Every item is hashed to a number in [0, N*M), the numbers are sorted
+// and the differences between them are Golomb-Rice coded: the quotient of a
+// difference divided by 2^P in unary followed by the remainder in P bits. With
+// M = 2^P the differences are about M apart, so every item takes about P+2
+// bits. A lookup hashes the query and decodes the set until it passes it.
+//
+// A query for an item that is not in the set matches with probability 1/M,
+// the chance that it hashes to the same number as one of the N items. BIP158
+// picks P = 19 and M = 784931, which minimizes the filter size for that false
+// positive rate.
+
+const (
+	filterP = 19     // Golomb-Rice parameter of block filters
+	filterM = 784931 // Inverse false positive rate of block filters
+)
+
+// bitWriter appends bits to a byte slice, most significant bit first.
+type bitWriter struct {
+	data []byte
+	n    uint // Number of bits written
+}
+
+// writeBit appends a single bit.
+func (w *bitWriter) writeBit(bit bool) {
+	if w.n%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if bit {
+		w.data[len(w.data)-1] |= 0x80 >> (w.n % 8)
+	}
+	w.n++
+}
+
+// writeBits appends the count least significant bits of x.
+func (w *bitWriter) writeBits(x uint64, count uint) {
+	for i := count; i > 0; i-- {
+		w.writeBit(x&(1<<(i-1)) != 0)
+	}
+}
+
+// bitReader reads bits from a byte slice, most significant bit first.
+type bitReader struct {
+	data []byte
+	n    uint // Number of bits read
+}
+
+// readBit returns the next bit.
+func (r *bitReader) readBit() (bool, error) {
+	if r.n/8 >= uint(len(r.data)) {
+		return false, fmt.Errorf("filter truncated")
+	}
+	bit := r.data[r.n/8]&(0x80>>(r.n%8)) != 0
+	r.n++
+	return bit, nil
+}
+
+// readBits returns the next count bits.
+func (r *bitReader) readBits(count uint) (uint64, error) {
+	var x uint64
+	for i := uint(0); i < count; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		x <<= 1
+		if bit {
+			x |= 1
+		}
+	}
+	return x, nil
+}
+
+// Filter is a Golomb-coded set.
+type Filter struct {
+	n    uint32   // Number of items
+	p    uint     // Golomb-Rice parameter
+	m    uint64   // Inverse false positive rate
+	key  [16]byte // SipHash key
+	data []byte   // Golomb-Rice coded differences
+}
+
+// hashItem maps item to a number in [0, N*M).
+func (f *Filter) hashItem(item []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(f.key[0:8])
+	k1 := binary.LittleEndian.Uint64(f.key[8:16])
+	// Multiply and keep the high word instead of a slow modulo.
+	hi, _ := bits.Mul64(sipHash(k0, k1, item), uint64(f.n)*f.m)
+	return hi
+}
+
+// NewFilter returns the Golomb-coded set of items with parameters p and m
+// that is keyed with key.
+func NewFilter(p uint, m uint64, key [16]byte, items [][]byte) *Filter {
+	f := &Filter{n: uint32(len(items)), p: p, m: m, key: key}
+	values := make([]uint64, 0, len(items))
+	for _, item := range items {
+		values = append(values, f.hashItem(item))
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	var w bitWriter
+	var last uint64
+	for _, v := range values {
+		delta := v - last
+		last = v
+		for q := delta >> p; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, p)
+	}
+	f.data = w.data
+	return f
+}
+
+// ParseFilter returns the filter with parameters p and m that is keyed with
+// key from its serialized form as returned by Bytes. The parameters and the key
+// are not part of the serialized filter and must be known to the caller.
+func ParseFilter(p uint, m uint64, key [16]byte, b []byte) (*Filter, error) {
+	n, l := binary.Uvarint(b)
+	if l <= 0 || n > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid filter size")
+	}
+	return &Filter{
+		n:    uint32(n),
+		p:    p,
+		m:    m,
+		key:  key,
+		data: append([]byte{}, b[l:]...),
+	}, nil
+}
+
+// N returns the number of items in the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// Bytes returns the serialized filter: the number of items as a varint
+// followed by the coded differences.
+func (f *Filter) Bytes() []byte {
+	var n [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(n[:], uint64(f.n))
+	return append(n[:l:l], f.data...)
+}
+
+// Hash returns the hash of the serialized filter.
+func (f *Filter) Hash() []byte {
+	return doubleSHA256(f.Bytes())
+}
+
+// MatchAny returns true if any of items is probably in the filter. False
+// positives occur with a probability of about len(items)/M.
+func (f *Filter) MatchAny(items [][]byte) (bool, error) {
+	if f.n == 0 || len(items) == 0 {
+		return false, nil
+	}
+	queries := make([]uint64, 0, len(items))
+	for _, item := range items {
+		queries = append(queries, f.hashItem(item))
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i] < queries[j]
+	})
+
+	// Walk the sorted queries and the sorted set in lockstep.
+	r := bitReader{data: f.data}
+	var value uint64
+	for i := uint32(0); i < f.n; i++ {
+		var q uint64
+		for {
+			bit, err := r.readBit()
+			if err != nil {
+				return false, err
+			}
+			if !bit {
+				break
+			}
+			q++
+		}
+		rem, err := r.readBits(f.p)
+		if err != nil {
+			return false, err
+		}
+		value += q<<f.p | rem
+
+		for len(queries) > 0 && queries[0] < value {
+			queries = queries[1:]
+		}
+		if len(queries) == 0 {
+			return false, nil
+		}
+		if queries[0] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Match returns true if item is probably in the filter.
+func (f *Filter) Match(item []byte) (bool, error) {
+	return f.MatchAny([][]byte{item})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Every node downloads, stores and verifies every block, so blocks must be
+// bounded. Without limits a miner could publish a block that takes everybody
+// else minutes to verify while it already mines on top of it. The limits are
+// consensus rules, not node policy: a node that accepts larger blocks than the
+// rest of the network follows a chain that everybody else rejects, which
+// splits the network in two. That is why changing the block size of a real
+// network takes a fork.
+//
+// Blocks are limited in three ways: the size of the serialized block, the
+// number of transactions and the number of signature operations, which are by
+// far the most expensive part of verifying a block.
+
+var (
+	// ErrBlockSize is returned for blocks that exceed MaxBlockSize.
+	ErrBlockSize = errors.New("block too large")
+
+	// ErrBlockTxs is returned for blocks that exceed MaxBlockTxs.
+	ErrBlockTxs = errors.New("too many transactions")
+
+	// ErrBlockSigOps is returned for blocks that exceed MaxBlockSigOps.
+	ErrBlockSigOps = errors.New("too many signature operations")
+)
+
+// size returns the size of the serialized transaction.
+func (tx *Tx) size() int {
+	return len(tx.serialize(false, 0, nil))
+}
+
+// countSigOps returns the number of signature operations in script. Like in
+// Bitcoin, OP_CHECKMULTISIG counts as the maximum number of public keys since
+// the actual number is only known when the script runs. A script that can't
+// be parsed can't run either and counts as none.
+func countSigOps(script []byte) int {
+	ops, err := parseScript(script)
+	if err != nil {
+		return 0
+	}
+	var n int
+	for _, op := range ops {
+		switch op.opcode {
+		case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+			n++
+		case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+			n += maxPubKeysPerMultiSig
+		}
+	}
+	return n
+}
+
+// sigOps returns the number of signature operations in the signature scripts
+// and public key scripts of the transaction.
+func (tx *Tx) sigOps() int {
+	var n int
+	for _, in := range tx.Inputs {
+		n += countSigOps(in.SignatureScript)
+	}
+	for _, out := range tx.Outputs {
+		n += countSigOps(out.PkScript)
+	}
+	return n
+}
+
+// blockCost tallies the resources a block uses.
+type blockCost struct {
+	size   int // Size of the serialized block
+	txs    int // Number of transactions
+	sigOps int // Number of signature operations
+}
+
+// add adds the cost of tx.
+func (c *blockCost) add(tx *Tx) {
+	c.size += tx.size()
+	c.txs++
+	c.sigOps += tx.sigOps()
+}
+
+// check returns an error that wraps ErrBlockSize, ErrBlockTxs or
+// ErrBlockSigOps if the cost exceeds the limits of params.
+func (c blockCost) check(params *Params) error {
+	if c.size > params.MaxBlockSize {
+		return fmt.Errorf("%w: %v bytes, maximum is %v", ErrBlockSize,
+			c.size, params.MaxBlockSize)
+	}
+	if c.txs > params.MaxBlockTxs {
+		return fmt.Errorf("%w: %v, maximum is %v", ErrBlockTxs, c.txs,
+			params.MaxBlockTxs)
+	}
+	if c.sigOps > params.MaxBlockSigOps {
+		return fmt.Errorf("%w: %v, maximum is %v", ErrBlockSigOps,
+			c.sigOps, params.MaxBlockSigOps)
+	}
+	return nil
+}
+
+// headerSize returns the size of the serialized header and nonce.
+func (b Block) headerSize() int {
+	return len(b.Header().serialize()) + 8
+}
+
+// cost returns the resources the block uses.
+func (b Block) cost() blockCost {
+	c := blockCost{size: b.headerSize()}
+	for _, tx := range b.Transactions {
+		c.add(tx)
+	}
+	return c
+}
+
+// Size returns the size of the serialized block: the header, the nonce and
+// all transactions.
+func (b Block) Size() int {
+	return b.cost().size
+}
+
+// SigOps returns the number of signature operations of the block.
+func (b Block) SigOps() int {
+	return b.cost().sigOps
+}
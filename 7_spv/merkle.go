@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The merkle root commits a block header to all of its transactions. A merkle
+// proof is the list of sibling hashes on the path from a transaction to the
+// root, so proving that a block contains a transaction takes log2(n) hashes
+// instead of all n transactions.
+
+// merkleParent returns the parent of two nodes.
+func merkleParent(left, right []byte) []byte {
+	return doubleSHA256(append(append([]byte{}, left...), right...))
+}
+
+// merkleRoot returns the root of the merkle tree of hashes. Every level
+// hashes pairs of nodes with double SHA256 and duplicates the last node of
+// levels of odd length.
+func merkleRoot(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		return Empty[:]
+	}
+	level := hashes
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, merkleParent(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// MerkleProof proves that a hash is a leaf of a merkle tree.
+type MerkleProof struct {
+	Index    int      // Position of the leaf
+	Siblings [][]byte // Sibling of every node on the path to the root
+}
+
+// NewMerkleProof returns the proof that hashes[index] is a leaf of the merkle
+// tree of hashes.
+func NewMerkleProof(hashes [][]byte, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(hashes) {
+		return nil, fmt.Errorf("invalid leaf: %v", index)
+	}
+	proof := &MerkleProof{Index: index}
+	level := hashes
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		proof.Siblings = append(proof.Siblings, level[index^1])
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, merkleParent(level[i], level[i+1]))
+		}
+		level = next
+		index /= 2
+	}
+	return proof, nil
+}
+
+// Root returns the merkle root that leaf and the proof hash to.
+func (p MerkleProof) Root(leaf []byte) []byte {
+	node := leaf
+	index := p.Index
+	for _, sibling := range p.Siblings {
+		if index%2 == 0 {
+			node = merkleParent(node, sibling)
+		} else {
+			node = merkleParent(sibling, node)
+		}
+		index /= 2
+	}
+	return node
+}
+
+// Verify returns true if leaf is part of the merkle tree with root.
+func (p MerkleProof) Verify(leaf, root []byte) bool {
+	return p.Index >= 0 && p.Index < 1<<uint(len(p.Siblings)) &&
+		bytes.Equal(p.Root(leaf), root)
+}
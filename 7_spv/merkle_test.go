@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var hashes [][]byte
+		for i := 0; i < n; i++ {
+			hashes = append(hashes, doubleSHA256([]byte{byte(i)}))
+		}
+		root := merkleRoot(hashes)
+		for i := range hashes {
+			proof, err := NewMerkleProof(hashes, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proof.Verify(hashes[i], root) {
+				t.Fatalf("%v leaves: proof of %v invalid", n,
+					i)
+			}
+			other := hashes[(i+1)%n]
+			if n > 1 && !bytes.Equal(other, hashes[i]) &&
+				proof.Verify(other, root) {
+				t.Fatalf("%v leaves: proof of %v verifies %x",
+					n, i, other)
+			}
+		}
+		proof, _ := NewMerkleProof(hashes, n-1)
+		t.Logf("%v leaves: %v siblings", n, len(proof.Siblings))
+	}
+
+	if _, err := NewMerkleProof(nil, 0); err == nil {
+		t.Fatalf("proof of empty tree")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// sipHash returns the SipHash-2-4 of blob under a 128 bit key as specified by
+// Aumasson and Bernstein. Compact block filters use it because it is fast and
+// keyed: without the key, which is derived from the block hash, nobody can
+// craft items that collide in a specific block's filter.
+func sipHash(k0, k1 uint64, blob []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	// Compress all full 8 byte words.
+	n := len(blob)
+	for len(blob) >= 8 {
+		m := binary.LittleEndian.Uint64(blob)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+		blob = blob[8:]
+	}
+
+	// The last word holds the remaining bytes and the length.
+	var last [8]byte
+	copy(last[:], blob)
+	last[7] = byte(n)
+	m := binary.LittleEndian.Uint64(last[:])
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
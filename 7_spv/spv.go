@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Simplified payment verification (SPV) as described in the Bitcoin paper. A
+// light client only downloads block headers, which are small and can be fully
+// validated: every header must link to the previous one and satisfy the
+// proof-of-work. Transactions that pay the client are proven to be part of a
+// block with a merkle proof against the merkle root in its header. The client
+// trusts that the chain with the most work only contains valid transactions,
+// which is why it waits for confirmations before accepting a payment.
+
+// HeaderChain is a chain of block headers without transactions.
+type HeaderChain struct {
+	params  *Params // Network parameters
+	clock   Clock   // Source of the current time
+	headers []BlockHeader
+	index   map[string]int // Header hash to height
+}
+
+// NewHeaderChain returns a header chain that starts at genesis. The genesis
+// header must carry the genesis data of the network.
+func NewHeaderChain(params *Params, clock Clock,
+	genesis BlockHeader) (*HeaderChain, error) {
+
+	if !bytes.Equal(genesis.Data, params.GenesisData) {
+		return nil, fmt.Errorf("genesis data mismatch")
+	}
+	h := &HeaderChain{
+		params: params,
+		clock:  clock,
+		index:  make(map[string]int),
+	}
+	if err := h.Append(genesis); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// medianTimePast returns the median timestamp of the up to medianTimeBlocks
+// headers that precede height.
+func (h *HeaderChain) medianTimePast(height int) int64 {
+	start := height - medianTimeBlocks
+	if start < 0 {
+		start = 0
+	}
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for _, header := range h.headers[start:height] {
+		timestamps = append(timestamps, header.Timestamp)
+	}
+	return median(timestamps)
+}
+
+// Append adds header, if valid, to the end of the chain. These are the same
+// checks that a full node performs on a block except for the transactions.
+func (h *HeaderChain) Append(header BlockHeader) error {
+	height := len(h.headers)
+	previousBlockHash := Empty[:]
+	if height > 0 {
+		previousBlockHash = h.headers[height-1].Hash
+	}
+	if !bytes.Equal(previousBlockHash, header.PreviousBlockHash) {
+		return fmt.Errorf("header does not link to previous header "+
+			"%x %x", previousBlockHash, header.PreviousBlockHash)
+	}
+	if !header.Verify() {
+		return fmt.Errorf("can't append invalid header")
+	}
+	if err := checkCheckpoint(h.params, height, header.Hash); err != nil {
+		return err
+	}
+	if !header.CheckProofOfWork(h.params.Difficulty) {
+		return fmt.Errorf("hash %x does not satisfy difficulty %v",
+			header.Hash, h.params.Difficulty)
+	}
+	if mtp := h.medianTimePast(height); header.Timestamp < mtp {
+		return fmt.Errorf("timestamp %v precedes median time past %v",
+			header.Timestamp, mtp)
+	}
+	maxTimestamp := h.clock.Now().Add(maxFutureDrift).Unix()
+	if header.Timestamp > maxTimestamp {
+		return fmt.Errorf("timestamp %v is more than %v ahead of the "+
+			"clock", header.Timestamp, maxFutureDrift)
+	}
+	h.index[string(header.Hash)] = height
+	h.headers = append(h.headers, header)
+	return nil
+}
+
+// Header returns the header at the specified height.
+func (h *HeaderChain) Header(height int) (BlockHeader, error) {
+	if height < 0 || height >= len(h.headers) {
+		return BlockHeader{}, fmt.Errorf("invalid header: %v", height)
+	}
+	return h.headers[height], nil
+}
+
+// HeaderByHash returns the header with the specified hash along with its
+// height.
+func (h *HeaderChain) HeaderByHash(hash []byte) (BlockHeader, int, error) {
+	height, ok := h.index[string(hash)]
+	if !ok {
+		return BlockHeader{}, 0, fmt.Errorf("header not found: %x",
+			hash)
+	}
+	return h.headers[height], height, nil
+}
+
+// Len returns the number of headers.
+func (h *HeaderChain) Len() int {
+	return len(h.headers)
+}
+
+// TxProof proves that a block contains a transaction.
+type TxProof struct {
+	BlockHash []byte       // Hash of the block that contains Tx
+	Tx        *Tx          // Proven transaction
+	Proof     *MerkleProof // Path from the transaction hash to the root
+}
+
+// Headers returns the headers of all blocks from height from on.
+func (b Blockchain) Headers(from int) []BlockHeader {
+	if from < 0 {
+		from = 0
+	}
+	var headers []BlockHeader
+	for i := from; i < len(b.blocks); i++ {
+		headers = append(headers, b.blocks[i].Header())
+	}
+	return headers
+}
+
+// TxProofs returns proofs for all transactions from height from on that pay
+// to one of pubKeyHashes.
+func (b Blockchain) TxProofs(pubKeyHashes [][]byte, from int) ([]*TxProof,
+	error) {
+
+	if from < 0 {
+		from = 0
+	}
+	var proofs []*TxProof
+	for i := from; i < len(b.blocks); i++ {
+		blk := b.blocks[i]
+		var hashes [][]byte
+		for _, tx := range blk.Transactions {
+			hashes = append(hashes, tx.Hash())
+		}
+		for j, tx := range blk.Transactions {
+			if !paysTo(tx, pubKeyHashes) {
+				continue
+			}
+			proof, err := NewMerkleProof(hashes, j)
+			if err != nil {
+				return nil, err
+			}
+			proofs = append(proofs, &TxProof{
+				BlockHash: blk.Hash,
+				Tx:        tx,
+				Proof:     proof,
+			})
+		}
+	}
+	return proofs, nil
+}
+
+// paysTo returns true if tx has an output that pays to one of pubKeyHashes.
+func paysTo(tx *Tx, pubKeyHashes [][]byte) bool {
+	for _, out := range tx.Outputs {
+		pkh, ok := ExtractPubKeyHash(out.PkScript)
+		if !ok {
+			continue
+		}
+		for _, h := range pubKeyHashes {
+			if bytes.Equal(pkh, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FullNode is what a light client needs from the node it syncs from. The
+// node is not trusted: everything it returns is verified.
+type FullNode interface {
+	// Headers returns all headers from height from on.
+	Headers(from int) []BlockHeader
+
+	// TxProofs returns proofs for the transactions from height from on
+	// that pay to one of pubKeyHashes.
+	TxProofs(pubKeyHashes [][]byte, from int) ([]*TxProof, error)
+}
+
+// Payment is a transaction output that pays a light client.
+type Payment struct {
+	Height  int      // Height of the block that contains the transaction
+	Tx      *Tx      // Paying transaction
+	Output  int      // Index of the output
+	Value   uint64   // Amount in atoms
+	Address *Address // Paid address
+}
+
+// LightClient follows a chain by its headers and finds payments to its
+// addresses.
+type LightClient struct {
+	headers   *HeaderChain
+	addresses []*Address
+	scanned   int                 // Height of the next block to scan
+	paid      map[string]struct{} // Outputs that have been reported
+
+	filterHeaders [][]byte // Filter headers that all peers agreed on
+}
+
+// NewLightClient returns a light client that watches addresses.
+func NewLightClient(headers *HeaderChain, addresses []*Address) *LightClient {
+	return &LightClient{
+		headers:   headers,
+		addresses: addresses,
+		paid:      make(map[string]struct{}),
+	}
+}
+
+// Headers returns the header chain of the client.
+func (c *LightClient) Headers() *HeaderChain {
+	return c.headers
+}
+
+// Sync downloads new headers and proofs of new payments from node and returns
+// the verified payments. Every output is reported once, even if node repeats a
+// proof within or across syncs.
+func (c *LightClient) Sync(node FullNode) ([]*Payment, error) {
+	for _, header := range node.Headers(c.headers.Len()) {
+		if err := c.headers.Append(header); err != nil {
+			return nil, err
+		}
+	}
+
+	pubKeyHashes := make([][]byte, 0, len(c.addresses))
+	for _, a := range c.addresses {
+		pubKeyHashes = append(pubKeyHashes, a.PubKeyHash)
+	}
+	proofs, err := node.TxProofs(pubKeyHashes, c.scanned)
+	if err != nil {
+		return nil, err
+	}
+	var payments []*Payment
+	for _, proof := range proofs {
+		verified, err := c.VerifyTxProof(proof)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range verified {
+			key := outPointKey(OutPoint{
+				Hash:  p.Tx.Hash(),
+				Index: uint32(p.Output),
+			})
+			if _, ok := c.paid[key]; ok {
+				continue
+			}
+			c.paid[key] = struct{}{}
+			payments = append(payments, p)
+		}
+	}
+	c.scanned = c.headers.Len()
+	return payments, nil
+}
+
+// VerifyTxProof verifies that proof proves that a block in the header chain
+// contains a transaction and returns the payments it makes to the client.
+func (c *LightClient) VerifyTxProof(proof *TxProof) ([]*Payment, error) {
+	header, height, err := c.headers.HeaderByHash(proof.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	txHash := proof.Tx.Hash()
+	if !proof.Proof.Verify(txHash, header.MerkleRoot) {
+		return nil, fmt.Errorf("invalid merkle proof for %x in "+
+			"block %x", txHash, proof.BlockHash)
+	}
+	var payments []*Payment
+	for i, out := range proof.Tx.Outputs {
+		pkh, ok := ExtractPubKeyHash(out.PkScript)
+		if !ok {
+			continue
+		}
+		for _, a := range c.addresses {
+			if !bytes.Equal(pkh, a.PubKeyHash) {
+				continue
+			}
+			payments = append(payments, &Payment{
+				Height:  height,
+				Tx:      proof.Tx,
+				Output:  i,
+				Value:   out.Value,
+				Address: a,
+			})
+		}
+	}
+	if len(payments) == 0 {
+		return nil, fmt.Errorf("transaction %x does not pay the client",
+			txHash)
+	}
+	return payments, nil
+}
+
+// Confirmations returns the number of blocks that confirm payment p.
+func (c *LightClient) Confirmations(p *Payment) int {
+	return c.headers.Len() - p.Height
+}
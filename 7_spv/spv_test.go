@@ -0,0 +1,266 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wallet is a single key along with the pay-to-pubkey-hash script that
+// locks outputs to it.
+type wallet struct {
+	key      *PrivateKey
+	address  *Address // Simnet address of the key
+	pkScript []byte   // Pay-to-pubkey-hash script
+}
+
+// newWallet returns a wallet with a new key from entropy.
+func newWallet(t *testing.T, entropy io.Reader) *wallet {
+	key, err := NewKey(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := NewPublicKey(key.Public()).Address(&SimNetParams)
+	pkScript, err := PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wallet{key: key, address: address, pkScript: pkScript}
+}
+
+// pay returns a transaction that spends output op of value atoms, which is
+// locked to the wallet, to pkScript. What is left after paying amount and
+// fee is returned to the wallet.
+func (w *wallet) pay(t *testing.T, op OutPoint, value uint64,
+	pkScript []byte, amount, fee uint64) *Tx {
+
+	tx := &Tx{
+		Inputs: []*TxIn{{PreviousOutPoint: op, Sequence: MaxSequence}},
+		Outputs: []*TxOut{
+			{Value: amount, PkScript: pkScript},
+			{Value: value - amount - fee, PkScript: w.pkScript},
+		},
+	}
+	var err error
+	tx.Inputs[0].SignatureScript, err = SignatureScript(tx, 0,
+		w.pkScript, w.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// mine advances clock by a minute and mines a block with txs onto b that
+// pays the coinbase to pkScript.
+func mine(t *testing.T, b *Blockchain, clock *ManualClock, pkScript []byte,
+	txs ...*Tx) *Block {
+
+	clock.Advance(time.Minute)
+	blk := b.PrepareBlock(pkScript, txs)
+	if err := b.Mine(blk); err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+// newLightClient returns a light client that watches addresses and trusts
+// the genesis block of b.
+func newLightClient(t *testing.T, b *Blockchain, clock Clock,
+	addresses ...*Address) *LightClient {
+
+	genesis, err := b.Block(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err := NewHeaderChain(b.Params(), clock, genesis.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLightClient(headers, addresses)
+}
+
+// repeatingNode is a full node that returns every proof twice and always
+// starts at genesis.
+type repeatingNode struct {
+	*Blockchain
+}
+
+// TxProofs returns all proofs twice.
+func (n repeatingNode) TxProofs(pubKeyHashes [][]byte, from int) ([]*TxProof,
+	error) {
+
+	proofs, err := n.Blockchain.TxProofs(pubKeyHashes, 0)
+	if err != nil {
+		return nil, err
+	}
+	return append(proofs, proofs...), nil
+}
+
+func TestLightClient(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	params := &SimNetParams
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(params, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := newWallet(t, entropy)
+	bob := newWallet(t, entropy)
+	miner := newWallet(t, entropy)
+
+	// Alice earns some coins and pays Bob in a block full of unrelated
+	// transactions.
+	coin := mine(t, b, clock, alice.pkScript)
+	var fillers []*Tx
+	for i := 0; i < 4; i++ {
+		blk := mine(t, b, clock, miner.pkScript)
+		fillers = append(fillers, miner.pay(t, coinbaseOutPoint(blk),
+			params.Subsidy, miner.pkScript, 1e8, 0))
+	}
+	payment := alice.pay(t, coinbaseOutPoint(coin), params.Subsidy,
+		bob.pkScript, 3e8, 1000)
+	mine(t, b, clock, miner.pkScript, fillers[0], fillers[1], payment,
+		fillers[2], fillers[3])
+
+	client := newLightClient(t, b, clock, bob.address)
+	payments, err := client.Sync(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 || payments[0].Value != 3e8 ||
+		payments[0].Output != 0 {
+		t.Fatalf("unexpected payments: %v", payments)
+	}
+	if client.Headers().Len() != b.Len() {
+		t.Fatalf("client has %v headers, chain %v blocks",
+			client.Headers().Len(), b.Len())
+	}
+	t.Logf("payment of %v to %v at height %v, %v confirmations",
+		payments[0].Value, payments[0].Address, payments[0].Height,
+		client.Confirmations(payments[0]))
+
+	// More blocks confirm the payment without reporting it again.
+	mine(t, b, clock, miner.pkScript)
+	mine(t, b, clock, miner.pkScript)
+	again, err := client.Sync(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("payment reported twice")
+	}
+	if c := client.Confirmations(payments[0]); c != 3 {
+		t.Fatalf("%v confirmations", c)
+	}
+
+	// A node that repeats proofs doesn't inflate the balance.
+	repeating := newLightClient(t, b, clock, bob.address)
+	for i := 0; i < 2; i++ {
+		payments, err := repeating.Sync(repeatingNode{b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(payments) != 1-i {
+			t.Fatalf("sync %v: %v payments", i, len(payments))
+		}
+	}
+
+	// The full node is not trusted with proofs.
+	proofs, err := b.TxProofs([][]byte{bob.address.PubKeyHash}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *proofs[0]
+	forgedTx := *forged.Tx
+	forgedTx.Outputs = []*TxOut{{Value: 1e9, PkScript: bob.pkScript}}
+	forged.Tx = &forgedTx
+	moved := *proofs[0]
+	moved.Proof = &MerkleProof{
+		Index:    moved.Proof.Index + 1,
+		Siblings: moved.Proof.Siblings,
+	}
+	unknown := *proofs[0]
+	unknown.BlockHash = Empty[:]
+	unrelated, err := b.TxProofs([][]byte{alice.address.PubKeyHash}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		proof *TxProof
+		err   string
+	}{
+		{"forged transaction", &forged, "invalid merkle proof"},
+		{"wrong index", &moved, "invalid merkle proof"},
+		{"unknown block", &unknown, "header not found"},
+		{"unrelated", unrelated[0], "does not pay the client"},
+	}
+	for _, test := range tests {
+		_, err := client.VerifyTxProof(test.proof)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		t.Logf("%v: %v", test.name, err)
+	}
+}
+
+func TestHeaderChain(t *testing.T) {
+	entropy := rand.New(rand.NewSource(1))
+	clock := NewManualClock(time.Unix(1600000000, 0))
+	b, err := NewBlockChain(&SimNetParams, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	miner := newWallet(t, entropy)
+	for i := 0; i < 3; i++ {
+		mine(t, b, clock, miner.pkScript)
+	}
+	client := newLightClient(t, b, clock)
+	headers := b.Headers(1)
+
+	unlinked := headers[1]
+	altered := headers[0]
+	altered.MerkleRoot = Empty[:]
+	weak := headers[0]
+	for weak.Nonce = 0; ; weak.Nonce++ {
+		weak.Hash = weak.calculateHash()
+		if !weak.CheckProofOfWork(SimNetParams.Difficulty) {
+			break
+		}
+	}
+	tests := []struct {
+		name   string
+		header BlockHeader
+		err    string
+	}{
+		{"unlinked", unlinked, "does not link"},
+		{"altered", altered, "invalid header"},
+		{"weak", weak, "does not satisfy difficulty"},
+	}
+	for _, test := range tests {
+		err := client.Headers().Append(test.header)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+	}
+	for _, header := range headers {
+		if err := client.Headers().Append(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := SimNetParams
+	other.GenesisData = []byte("other")
+	genesis, _ := b.Block(0)
+	_, err = NewHeaderChain(&other, clock, genesis.Header())
+	if err == nil {
+		t.Fatalf("genesis of another network accepted")
+	}
+}
+
+// coinbaseOutPoint returns the output of the coinbase of blk.
+func coinbaseOutPoint(blk *Block) OutPoint {
+	return OutPoint{Hash: blk.Transactions[0].Hash(), Index: 0}
+}
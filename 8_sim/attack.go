@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// NakamotoProbability returns the probability that an attacker with fraction
+// q of the hash power ever catches up with an honest chain after the merchant
+// waited for z confirmations, as calculated in section 11 of the Bitcoin paper.
+// The attacker's progress while the honest miners find z blocks is Poisson
+// distributed and from a deficit of d blocks it catches up with probability
+// (q/p)^d.
+func NakamotoProbability(q float64, z int) float64 {
+	p := 1 - q
+	if q >= p {
+		return 1
+	}
+	lambda := float64(z) * q / p
+	sum := 1.0
+	poisson := math.Exp(-lambda)
+	for k := 0; k <= z; k++ {
+		if k > 0 {
+			poisson *= lambda / float64(k)
+		}
+		sum -= poisson * (1 - math.Pow(q/p, float64(z-k)))
+	}
+	return sum
+}
+
+// SelfishRevenue returns the expected share of the main chain of a selfish
+// miner with fraction alpha of the hash power. Gamma is the fraction of the
+// honest miners that mine on the selfish block when two blocks race. The
+// formula is from Eyal and Sirer.
+func SelfishRevenue(alpha, gamma float64) float64 {
+	a, g := alpha, gamma
+	num := a*(1-a)*(1-a)*(4*a+g*(1-2*a)) - a*a*a
+	den := 1 - a*(1+(2-a)*a)
+	return num / den
+}
+
+// DoubleSpendConfig describes a series of double spend attacks.
+type DoubleSpendConfig struct {
+	Seed          int64         // Seed of the first trial
+	Trials        int           // Number of attacks
+	Share         float64       // Attacker's fraction of the hash power
+	Confirmations int           // Blocks the merchant waits for
+	GiveUp        int           // Blocks the attacker may fall behind
+	BlockInterval time.Duration // Average time between blocks
+	Delay         time.Duration // Average propagation delay
+}
+
+// DoubleSpendResult is the outcome of a series of double spend attacks.
+type DoubleSpendResult struct {
+	Trials    int // Number of attacks
+	Successes int // Attacks that reversed the payment
+}
+
+// Rate returns the fraction of successful attacks.
+func (r *DoubleSpendResult) Rate() float64 {
+	if r.Trials == 0 {
+		return 0
+	}
+	return float64(r.Successes) / float64(r.Trials)
+}
+
+// settle is the number of blocks that are mined after an attack ended before
+// it is judged. It gives a released chain time to reach the honest miners.
+const settle = 6
+
+// RunDoubleSpend runs config.Trials attacks, each with its own seed, and
+// counts how often the payment ends up outside the chain of the honest miners.
+func RunDoubleSpend(config DoubleSpendConfig) (*DoubleSpendResult, error) {
+	if config.Confirmations < 0 || config.GiveUp < 0 {
+		return nil, fmt.Errorf("invalid confirmations or give up")
+	}
+	r := &DoubleSpendResult{}
+	for i := 0; i < config.Trials; i++ {
+		honest := &Miner{Name: "honest", Share: 1 - config.Share,
+			Strategy: Honest{}}
+		attacker := &Miner{Name: "attacker", Share: config.Share,
+			Strategy: DoubleSpend{
+				Confirmations: config.Confirmations,
+				GiveUp:        config.GiveUp,
+			}}
+		s, err := NewSimulator(Config{
+			Seed:          config.Seed + int64(i),
+			Blocks:        math.MaxInt32,
+			BlockInterval: config.BlockInterval,
+			Delay:         config.Delay,
+			Miners:        []*Miner{honest, attacker},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for !attacker.done && s.Step() {
+		}
+		s.config.Blocks = len(s.blocks) + settle
+		for s.Step() {
+		}
+
+		r.Trials++
+		if !honest.public.contains(attacker.payment) {
+			r.Successes++
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// The simulator is a discrete-event simulation of miners that are connected
+// by a network with propagation delays. Blocks are found as a Poisson process:
+// the time to the next block is exponentially distributed around the block
+// interval and the miner that finds it is picked in proportion to its share
+// of the hash power. Every block that a miner publishes reaches every other
+// miner after a random delay.
+//
+// The simulation does not mine real blocks. It only keeps the block tree,
+// which is all that is needed to see forks, orphans and who earns what. All
+// randomness comes from a single seeded source, so a scenario is reproduced
+// exactly by running it with the same seed.
+
+// simBlock is a block in the tree of all mined blocks.
+type simBlock struct {
+	id        int       // Position in the order blocks were mined
+	parent    *simBlock // Previous block, nil for genesis
+	height    int       // Height of the block
+	miner     int       // Miner that found the block, -1 for genesis
+	time      float64   // Seconds since the start of the simulation
+	published bool      // True once the block was sent to the network
+}
+
+// ancestor returns the ancestor of b at height.
+func (b *simBlock) ancestor(height int) *simBlock {
+	for b != nil && b.height > height {
+		b = b.parent
+	}
+	if b == nil || b.height != height {
+		return nil
+	}
+	return b
+}
+
+// contains returns true if the chain that ends in b contains block.
+func (b *simBlock) contains(block *simBlock) bool {
+	return b.ancestor(block.height) == block
+}
+
+// unpublished returns the unpublished blocks of the chain that ends in b up
+// to height, oldest first.
+func (b *simBlock) unpublished(height int) []*simBlock {
+	var blocks []*simBlock
+	for ; b != nil && !b.published; b = b.parent {
+		if b.height <= height {
+			blocks = append([]*simBlock{b}, blocks...)
+		}
+	}
+	return blocks
+}
+
+// Miner is a participant of the simulation.
+type Miner struct {
+	ID       int      // Index of the miner
+	Name     string   // Human readable name
+	Share    float64  // Fraction of the total hash power
+	Strategy Strategy // Decides what to mine on and what to publish
+
+	known  map[int]struct{} // Blocks the miner knows about
+	public *simBlock        // Best published chain the miner has seen
+	tip    *simBlock        // Block the miner mines on
+
+	branch  int       // Length of the private branch of a selfish miner
+	payment *simBlock // Block with the payment a double spender reverses
+	done    bool      // True once a double spend attack is over
+}
+
+// learn marks b and all its ancestors as known.
+func (m *Miner) learn(b *simBlock) {
+	for ; b != nil; b = b.parent {
+		if _, ok := m.known[b.id]; ok {
+			return
+		}
+		m.known[b.id] = struct{}{}
+	}
+}
+
+// knows returns true if the miner knows b.
+func (m *Miner) knows(b *simBlock) bool {
+	_, ok := m.known[b.id]
+	return ok
+}
+
+// Config describes a scenario.
+type Config struct {
+	Seed          int64         // Seed of all randomness
+	Blocks        int           // Number of blocks to mine
+	BlockInterval time.Duration // Average time between blocks
+	Delay         time.Duration // Average propagation delay
+	Miners        []*Miner      // Participants, shares must add up to 1
+}
+
+// eventKind identifies what happens at an event.
+type eventKind int
+
+const (
+	eventMined   eventKind = iota // Next block is found
+	eventArrival                  // A block reaches a miner
+)
+
+// event is something that happens at a point in time.
+type event struct {
+	time  float64   // Seconds since the start of the simulation
+	seq   int       // Scheduling order, breaks ties in time
+	kind  eventKind // What happens
+	miner int       // Receiving miner of an arrival
+	block *simBlock // Arriving block
+}
+
+// eventQueue is a priority queue of events ordered by time.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Simulator runs a scenario.
+type Simulator struct {
+	config  Config
+	rng     *rand.Rand
+	now     float64     // Current time in seconds
+	seq     int         // Number of scheduled events
+	queue   eventQueue  // Pending events
+	genesis *simBlock   // Root of the block tree
+	blocks  []*simBlock // All blocks in the order they were mined
+}
+
+// NewSimulator returns a simulator for config.
+func NewSimulator(config Config) (*Simulator, error) {
+	if len(config.Miners) == 0 {
+		return nil, fmt.Errorf("no miners")
+	}
+	if config.BlockInterval <= 0 || config.Delay < 0 {
+		return nil, fmt.Errorf("invalid block interval or delay")
+	}
+	var total float64
+	for _, m := range config.Miners {
+		if m.Share <= 0 {
+			return nil, fmt.Errorf("miner %v has no hash power",
+				m.Name)
+		}
+		total += m.Share
+	}
+	if total < 0.999 || total > 1.001 {
+		return nil, fmt.Errorf("shares add up to %v", total)
+	}
+
+	genesis := &simBlock{miner: -1, published: true}
+	s := &Simulator{
+		config:  config,
+		rng:     rand.New(rand.NewSource(config.Seed)),
+		genesis: genesis,
+	}
+	for i, m := range config.Miners {
+		m.ID = i
+		m.known = map[int]struct{}{genesis.id: {}}
+		m.public = genesis
+		m.tip = genesis
+		m.branch = 0
+		m.payment = nil
+		m.done = false
+	}
+	s.scheduleMining()
+	return s, nil
+}
+
+// schedule adds e to the queue.
+func (s *Simulator) schedule(e *event) {
+	e.seq = s.seq
+	s.seq++
+	heap.Push(&s.queue, e)
+}
+
+// scheduleMining schedules the next block.
+func (s *Simulator) scheduleMining() {
+	interval := s.config.BlockInterval.Seconds()
+	s.schedule(&event{
+		time: s.now + s.rng.ExpFloat64()*interval,
+		kind: eventMined,
+	})
+}
+
+// pickMiner returns a random miner weighted by hash power.
+func (s *Simulator) pickMiner() *Miner {
+	x := s.rng.Float64()
+	for _, m := range s.config.Miners {
+		if x < m.Share {
+			return m
+		}
+		x -= m.Share
+	}
+	return s.config.Miners[len(s.config.Miners)-1]
+}
+
+// publish sends blocks from miner from to all other miners.
+func (s *Simulator) publish(from *Miner, blocks []*simBlock) {
+	delay := s.config.Delay.Seconds()
+	for _, b := range blocks {
+		b.published = true
+		for _, m := range s.config.Miners {
+			if m == from {
+				continue
+			}
+			s.schedule(&event{
+				time:  s.now + delay*(0.5+s.rng.Float64()),
+				kind:  eventArrival,
+				miner: m.ID,
+				block: b,
+			})
+		}
+	}
+}
+
+// Step processes the next event and returns false once the configured number
+// of blocks has been mined.
+func (s *Simulator) Step() bool {
+	if len(s.blocks) >= s.config.Blocks || s.queue.Len() == 0 {
+		return false
+	}
+	e := heap.Pop(&s.queue).(*event)
+	s.now = e.time
+	switch e.kind {
+	case eventMined:
+		m := s.pickMiner()
+		b := &simBlock{
+			id:     len(s.blocks) + 1,
+			parent: m.tip,
+			height: m.tip.height + 1,
+			miner:  m.ID,
+			time:   s.now,
+		}
+		s.blocks = append(s.blocks, b)
+		m.learn(b)
+		s.publish(m, m.Strategy.Mined(m, b))
+		s.scheduleMining()
+
+	case eventArrival:
+		m := s.config.Miners[e.miner]
+		if m.knows(e.block) {
+			break
+		}
+		m.learn(e.block)
+		s.publish(m, m.Strategy.Received(m, e.block))
+	}
+	return true
+}
+
+// Run runs the scenario to completion and returns its result.
+func (s *Simulator) Run() *Result {
+	for s.Step() {
+	}
+	return s.Result()
+}
+
+// bestChain returns the tip of the longest published chain that any miner
+// has seen. Ties go to the miner that is listed first.
+func (s *Simulator) bestChain() *simBlock {
+	best := s.genesis
+	for _, m := range s.config.Miners {
+		if m.public.height > best.height {
+			best = m.public
+		}
+	}
+	return best
+}
+
+// MinerResult is the outcome of a scenario for a single miner.
+type MinerResult struct {
+	Name    string  // Name of the miner
+	Share   float64 // Fraction of the total hash power
+	Mined   int     // Blocks found
+	Main    int     // Blocks in the main chain
+	Revenue float64 // Fraction of the main chain
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Mined   int            // Blocks found
+	Main    int            // Blocks in the main chain
+	Orphans int            // Blocks that are not in the main chain
+	Miners  []*MinerResult // Outcome of every miner
+}
+
+// OrphanRate returns the fraction of found blocks that are not in the main
+// chain.
+func (r *Result) OrphanRate() float64 {
+	if r.Mined == 0 {
+		return 0
+	}
+	return float64(r.Orphans) / float64(r.Mined)
+}
+
+// Result returns the outcome of the blocks that have been mined so far.
+func (s *Simulator) Result() *Result {
+	r := &Result{Mined: len(s.blocks)}
+	for _, m := range s.config.Miners {
+		r.Miners = append(r.Miners, &MinerResult{
+			Name:  m.Name,
+			Share: m.Share,
+		})
+	}
+	best := s.bestChain()
+	r.Main = best.height
+	for _, b := range s.blocks {
+		r.Miners[b.miner].Mined++
+		if best.contains(b) {
+			r.Miners[b.miner].Main++
+		} else {
+			r.Orphans++
+		}
+	}
+	for _, m := range r.Miners {
+		if r.Main > 0 {
+			m.Revenue = float64(m.Main) / float64(r.Main)
+		}
+	}
+	return r
+}
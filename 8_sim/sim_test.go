@@ -0,0 +1,205 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	blockInterval = 10 * time.Minute
+	delay         = 2 * time.Second
+)
+
+// honestMiners returns n honest miners that share the hash power equally.
+func honestMiners(n int) []*Miner {
+	var miners []*Miner
+	for i := 0; i < n; i++ {
+		miners = append(miners, &Miner{
+			Name:     "honest",
+			Share:    1 / float64(n),
+			Strategy: Honest{},
+		})
+	}
+	return miners
+}
+
+// run runs a scenario and fails the test on error.
+func run(t *testing.T, seed int64, blocks int, delay time.Duration,
+	miners []*Miner) *Result {
+
+	s, err := NewSimulator(Config{
+		Seed:          seed,
+		Blocks:        blocks,
+		BlockInterval: blockInterval,
+		Delay:         delay,
+		Miners:        miners,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Run()
+}
+
+// logResult logs the outcome of a scenario.
+func logResult(t *testing.T, r *Result) {
+	t.Logf("mined %v, main chain %v, orphan rate %.4f", r.Mined, r.Main,
+		r.OrphanRate())
+	for _, m := range r.Miners {
+		t.Logf("  %-10v share %.2f mined %5v main %5v revenue %.4f",
+			m.Name, m.Share, m.Mined, m.Main, m.Revenue)
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	miners := func() []*Miner {
+		return []*Miner{
+			{Name: "honest", Share: 0.6, Strategy: Honest{}},
+			{Name: "selfish", Share: 0.4, Strategy: Selfish{}},
+		}
+	}
+
+	r1 := run(t, 1, 1000, delay, miners())
+	r2 := run(t, 1, 1000, delay, miners())
+	if !reflect.DeepEqual(r1, r2) {
+		t.Fatalf("same seed, different results")
+	}
+	r3 := run(t, 2, 1000, delay, miners())
+	if reflect.DeepEqual(r1, r3) {
+		t.Fatalf("different seed, same results")
+	}
+}
+
+func TestHonest(t *testing.T) {
+	tests := []struct {
+		delay     time.Duration
+		maxOrphan float64
+	}{
+		{delay, 0.01},
+		{time.Minute, 0.15},
+	}
+	for _, test := range tests {
+		t.Logf("propagation delay %v", test.delay)
+		r := run(t, 1, 10000, test.delay, honestMiners(5))
+		logResult(t, r)
+		if r.OrphanRate() > test.maxOrphan {
+			t.Fatalf("orphan rate %v", r.OrphanRate())
+		}
+		if r.Main+r.Orphans != r.Mined {
+			t.Fatalf("blocks don't add up")
+		}
+		for _, m := range r.Miners {
+			if math.Abs(m.Revenue-m.Share) > 0.03 {
+				t.Fatalf("revenue %v, share %v", m.Revenue,
+					m.Share)
+			}
+		}
+	}
+	// Slower propagation means more forks.
+	fast := run(t, 1, 10000, delay, honestMiners(5))
+	slow := run(t, 1, 10000, time.Minute, honestMiners(5))
+	if slow.OrphanRate() <= fast.OrphanRate() {
+		t.Fatalf("orphan rate did not increase with delay")
+	}
+}
+
+func TestSelfish(t *testing.T) {
+	tests := []struct {
+		share      float64
+		profitable bool
+	}{
+		{0.1, false},
+		{0.25, false},
+		{0.4, true},
+		{0.45, true},
+	}
+	for _, test := range tests {
+		miners := honestMiners(4)
+		for _, m := range miners {
+			m.Share = (1 - test.share) / 4
+		}
+		miners = append(miners, &Miner{
+			Name:     "selfish",
+			Share:    test.share,
+			Strategy: Selfish{},
+		})
+		r := run(t, 1, 10000, delay, miners)
+		revenue := r.Miners[len(miners)-1].Revenue
+		expected := SelfishRevenue(test.share, 0)
+		t.Logf("share %.2f revenue %.4f expected %.4f orphan rate %.4f",
+			test.share, revenue, expected, r.OrphanRate())
+		if math.Abs(revenue-expected) > 0.03 {
+			t.Fatalf("revenue %v, expected %v", revenue, expected)
+		}
+		if (revenue > test.share) != test.profitable {
+			t.Fatalf("revenue %v for share %v", revenue, test.share)
+		}
+	}
+}
+
+func TestNakamotoProbability(t *testing.T) {
+	// Values from section 11 of the Bitcoin paper.
+	tests := []struct {
+		q float64
+		z int
+		p float64
+	}{
+		{0.1, 0, 1},
+		{0.1, 1, 0.2045873},
+		{0.1, 5, 0.0009137},
+		{0.3, 5, 0.1773523},
+		{0.3, 10, 0.0416605},
+	}
+	for _, test := range tests {
+		p := NakamotoProbability(test.q, test.z)
+		if math.Abs(p-test.p) > 1e-6 {
+			t.Fatalf("q=%v z=%v: got %v want %v", test.q, test.z, p,
+				test.p)
+		}
+	}
+}
+
+func TestDoubleSpend(t *testing.T) {
+	tests := []struct {
+		share         float64
+		confirmations int
+	}{
+		{0.1, 1},
+		{0.3, 1},
+		{0.3, 3},
+		{0.3, 6},
+		{0.6, 6},
+	}
+	var last float64
+	for i, test := range tests {
+		r, err := RunDoubleSpend(DoubleSpendConfig{
+			Seed:          1,
+			Trials:        500,
+			Share:         test.share,
+			Confirmations: test.confirmations,
+			GiveUp:        20,
+			BlockInterval: blockInterval,
+			Delay:         delay,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nakamoto := NakamotoProbability(test.share, test.confirmations)
+		t.Logf("share %.2f confirmations %v success %.3f nakamoto %.3f",
+			test.share, test.confirmations, r.Rate(), nakamoto)
+
+		// The attacker has to get ahead, not just catch up, so it does
+		// a little worse than the paper's estimate.
+		if r.Rate() > nakamoto+0.03 {
+			t.Fatalf("success rate %v above %v", r.Rate(), nakamoto)
+		}
+		if i > 1 && test.share == tests[i-1].share && r.Rate() >= last {
+			t.Fatalf("more confirmations did not help")
+		}
+		last = r.Rate()
+	}
+	if last < 0.9 {
+		t.Fatalf("majority attacker failed: %v", last)
+	}
+}
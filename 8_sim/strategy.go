@@ -0,0 +1,156 @@
+package main
+
+// Strategy decides what a miner mines on and which blocks it publishes. The
+// simulator calls Mined when the miner finds block b and Received when a block
+// from another miner arrives. Both return the blocks the miner publishes.
+type Strategy interface {
+	// Name returns a human readable name of the strategy.
+	Name() string
+
+	// Mined is called when miner m found block b.
+	Mined(m *Miner, b *simBlock) []*simBlock
+
+	// Received is called when block b from another miner reaches m.
+	Received(m *Miner, b *simBlock) []*simBlock
+}
+
+// Honest follows the protocol: it mines on the longest chain it has seen,
+// prefers the block it saw first when two chains are equally long and
+// publishes every block it finds right away.
+type Honest struct{}
+
+// Name returns the name of the strategy.
+func (Honest) Name() string {
+	return "honest"
+}
+
+// Mined publishes b and mines on top of it.
+func (Honest) Mined(m *Miner, b *simBlock) []*simBlock {
+	m.public = b
+	m.tip = b
+	return []*simBlock{b}
+}
+
+// Received switches to the chain of b if it is longer.
+func (Honest) Received(m *Miner, b *simBlock) []*simBlock {
+	if b.height > m.public.height {
+		m.public = b
+		m.tip = b
+	}
+	return nil
+}
+
+// Selfish withholds the blocks it finds and publishes them only to orphan the
+// blocks of the honest miners, as described by Eyal and Sirer in "Majority is
+// not Enough: Bitcoin Mining is Vulnerable". Honest miners waste their work on
+// blocks that end up orphaned, which makes the selfish miner's share of the
+// main chain larger than its share of the hash power once it has more than
+// about a third of it.
+type Selfish struct{}
+
+// Name returns the name of the strategy.
+func (Selfish) Name() string {
+	return "selfish"
+}
+
+// Mined extends the private branch. If the honest miners just caught up and
+// the race between two equally long chains is on, the new block wins it and
+// the whole branch is published.
+func (Selfish) Mined(m *Miner, b *simBlock) []*simBlock {
+	lead := m.tip.height - m.public.height
+	m.tip = b
+	m.branch++
+	if lead == 0 && m.branch == 2 {
+		m.branch = 0
+		m.public = b
+		return b.unpublished(b.height)
+	}
+	return nil
+}
+
+// Received reacts to a longer public chain. A selfish miner that falls behind
+// gives up its branch, one that is caught up races with its last block, one
+// that is a block ahead publishes everything and wins and one that is further
+// ahead publishes just enough to keep the honest miners busy.
+func (Selfish) Received(m *Miner, b *simBlock) []*simBlock {
+	if b.height <= m.public.height {
+		return nil
+	}
+	m.public = b
+	switch lead := m.tip.height - b.height; {
+	case lead < 0:
+		m.tip = b
+		m.branch = 0
+		return nil
+	case lead == 0:
+		return m.tip.unpublished(m.tip.height)
+	case lead == 1:
+		m.branch = 0
+		m.public = m.tip
+		return m.tip.unpublished(m.tip.height)
+	default:
+		return m.tip.unpublished(b.height)
+	}
+}
+
+// DoubleSpend pays a merchant in the first block of the public chain and
+// secretly mines a chain without that payment. Once the merchant has seen the
+// payment confirmed and the secret chain is longer than the public one, the
+// attacker publishes it, which reverses the payment. With more than half of
+// the hash power this always succeeds eventually, which is the 51% attack.
+// Otherwise the attacker gives up when it falls too far behind.
+type DoubleSpend struct {
+	Confirmations int // Blocks the merchant waits for after the payment
+	GiveUp        int // Blocks the attacker may fall behind
+}
+
+// Name returns the name of the strategy.
+func (DoubleSpend) Name() string {
+	return "double spend"
+}
+
+// confirmed returns true once the merchant considers the payment final.
+func (s DoubleSpend) confirmed(m *Miner) bool {
+	return m.payment != nil &&
+		m.public.height-m.payment.height >= s.Confirmations
+}
+
+// release publishes the secret chain if it reverses a confirmed payment.
+func (s DoubleSpend) release(m *Miner) []*simBlock {
+	if !s.confirmed(m) || m.tip.height <= m.public.height {
+		return nil
+	}
+	m.done = true
+	m.public = m.tip
+	return m.tip.unpublished(m.tip.height)
+}
+
+// Mined extends the secret chain and releases it once it is good enough.
+func (s DoubleSpend) Mined(m *Miner, b *simBlock) []*simBlock {
+	if m.done {
+		return Honest{}.Mined(m, b)
+	}
+	m.tip = b
+	return s.release(m)
+}
+
+// Received tracks the public chain, which contains the payment, and gives up
+// when the secret chain falls too far behind.
+func (s DoubleSpend) Received(m *Miner, b *simBlock) []*simBlock {
+	if m.done {
+		return Honest{}.Received(m, b)
+	}
+	if b.height <= m.public.height {
+		return nil
+	}
+	m.public = b
+	if m.payment == nil {
+		m.payment = b.ancestor(1)
+	}
+	if m.public.height-m.tip.height > s.GiveUp {
+		m.done = true
+		m.tip = b
+		return nil
+	}
+	return s.release(m)
+}